@@ -13,6 +13,7 @@ import (
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/tasks/v1"
 )
 
 // getClient uses a Context and Config to retrieve a Token
@@ -32,7 +33,7 @@ func getClient() (*http.Client, error) {
 		return nil, fmt.Errorf("unable to read client secret file at %s: %v", credsPath, err)
 	}
 
-	config, err := google.ConfigFromJSON(b, calendar.CalendarReadonlyScope)
+	config, err := google.ConfigFromJSON(b, calendar.CalendarReadonlyScope, tasks.TasksScope)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse client secret file to config: %v", err)
 	}
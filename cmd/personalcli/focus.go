@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// focusHookEnvVar sets the default hook command for "focus".
+const focusHookEnvVar = "PERSONALCLI_FOCUS_HOOK"
+
+// focusBarWidth is the length of the countdown progress bar.
+const focusBarWidth = 20
+
+// pomodoro is one focus session spent on a task. Partial sessions were cut
+// short with Ctrl-C.
+type pomodoro struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Partial bool      `json:"partial,omitempty"`
+}
+
+// completedPomodoros counts the full focus sessions recorded on a task.
+func completedPomodoros(task Task) int {
+	n := 0
+	for _, p := range task.Pomodoros {
+		if !p.Partial {
+			n++
+		}
+	}
+	return n
+}
+
+// recordPomodoro adds a focus session to a task. The list is read again for
+// every session, so edits made while the timer runs are kept.
+func recordPomodoro(taskID int, p pomodoro) error {
+	tasks, err := readTasks()
+	if err != nil {
+		return err
+	}
+	taskIndex := findTask(tasks, taskID)
+	if taskIndex == -1 {
+		return fmt.Errorf("task %d no longer exists", taskID)
+	}
+	tasks[taskIndex].Pomodoros = append(tasks[taskIndex].Pomodoros, p)
+	return saveTasks(fmt.Sprintf("focus %d", taskID), tasks)
+}
+
+// runFocusHook runs the user's hook command through the shell at a phase
+// change, describing the phase in environment variables. Hooks run to
+// completion before the next phase starts, so slow ones should background
+// themselves.
+func runFocusHook(hook, phase string, round int, length time.Duration, task *Task) {
+	if hook == "" {
+		return
+	}
+	cmd := exec.Command("sh", "-c", hook)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"PERSONALCLI_FOCUS_PHASE="+phase,
+		"PERSONALCLI_FOCUS_ROUND="+strconv.Itoa(round),
+		"PERSONALCLI_FOCUS_MINUTES="+strconv.Itoa(int(length.Round(time.Minute).Minutes())),
+	)
+	if task != nil {
+		cmd.Env = append(cmd.Env,
+			"PERSONALCLI_FOCUS_TASK="+strconv.Itoa(task.ID),
+			"PERSONALCLI_FOCUS_DESCRIPTION="+task.Description)
+	}
+	if err := cmd.Run(); err != nil {
+		fmt.Printf("\nWarning: focus hook failed: %v\n", err)
+	}
+}
+
+// formatCountdown renders the time left in a phase as minutes and seconds.
+func formatCountdown(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// runPhase counts down one phase, redrawing the countdown every second when
+// stdout is a terminal. It returns how long the phase ran and whether it was
+// interrupted.
+func runPhase(label string, length time.Duration, interrupt <-chan os.Signal) (time.Duration, bool) {
+	live := isTerminal(os.Stdout)
+	if !live {
+		fmt.Printf("%s (%s)\n", label, formatCountdown(length))
+	}
+	draw := func(elapsed time.Duration) {
+		if !live {
+			return
+		}
+		done := min(focusBarWidth, int(elapsed*focusBarWidth/length))
+		fmt.Printf("\r\033[K%s  %s  %s%s", label, formatCountdown(length-elapsed),
+			strings.Repeat("█", done), strings.Repeat("░", focusBarWidth-done))
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	end := time.NewTimer(length)
+	defer end.Stop()
+	draw(0)
+	for {
+		select {
+		case <-ticker.C:
+			draw(time.Since(start))
+		case <-end.C:
+			draw(length)
+			if live {
+				fmt.Println()
+			}
+			return length, false
+		case <-interrupt:
+			if live {
+				fmt.Println()
+			}
+			return time.Since(start), true
+		}
+	}
+}
+
+var focusCmd = &cobra.Command{
+	Use:   "focus [task_id]",
+	Short: "Run a pomodoro timer, optionally recording the sessions on a task",
+	Long: `Run pomodoro cycles: a work phase followed by a short break, with a long
+break after every few rounds, and a live countdown in the terminal.
+
+With a task ID each finished work phase is recorded as a pomodoro on that
+task, looked up in the list given with --list or else the default list.
+Pressing Ctrl-C ends the timer and records the work done so far as a
+partial session.
+
+A hook command (--hook or $PERSONALCLI_FOCUS_HOOK) runs through the shell at
+every phase change with PERSONALCLI_FOCUS_PHASE set to work, break,
+long-break, done or stopped, along with PERSONALCLI_FOCUS_ROUND,
+PERSONALCLI_FOCUS_MINUTES and, for a task, PERSONALCLI_FOCUS_TASK and
+PERSONALCLI_FOCUS_DESCRIPTION. For example:
+
+  personalcli focus 3 --hook 'notify-send "Pomodoro" "$PERSONALCLI_FOCUS_PHASE"'`,
+	Args:    cobra.MaximumNArgs(1),
+	PreRunE: useListFlag,
+	Run: func(cmd *cobra.Command, args []string) {
+		work, _ := cmd.Flags().GetDuration("work")
+		shortBreak, _ := cmd.Flags().GetDuration("break")
+		longBreak, _ := cmd.Flags().GetDuration("long-break")
+		longEvery, _ := cmd.Flags().GetInt("long-every")
+		rounds, _ := cmd.Flags().GetInt("rounds")
+		hook, _ := cmd.Flags().GetString("hook")
+		if work <= 0 || shortBreak < 0 || longBreak < 0 || longEvery < 1 || rounds < 0 {
+			fmt.Println("Error: --work must be positive, breaks and --rounds not negative and --long-every at least 1.")
+			os.Exit(1)
+		}
+
+		var task *Task
+		if len(args) == 1 {
+			taskID, err := strconv.Atoi(args[0])
+			if err != nil {
+				fmt.Println("Invalid task ID. Please provide a number.")
+				os.Exit(1)
+			}
+			tasks, err := readTasks()
+			if err != nil {
+				fmt.Println("Error reading tasks:", err)
+				os.Exit(1)
+			}
+			taskIndex := findTask(tasks, taskID)
+			if taskIndex == -1 {
+				fmt.Println("Task ID not found.")
+				os.Exit(1)
+			}
+			if tasks[taskIndex].isDone() {
+				fmt.Printf("Task %d is already done.\n", taskID)
+				os.Exit(1)
+			}
+			task = &tasks[taskIndex]
+			fmt.Printf("Focusing on task %d: %s\n", task.ID, task.Description)
+		}
+
+		// Ctrl-C ends the current phase instead of killing the process, so
+		// the session can still be recorded.
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(interrupt)
+
+		roundLabel := func(round int) string {
+			if rounds == 0 {
+				return strconv.Itoa(round)
+			}
+			return fmt.Sprintf("%d/%d", round, rounds)
+		}
+
+		completed := 0
+		var focused time.Duration
+		for round := 1; rounds == 0 || round <= rounds; round++ {
+			runFocusHook(hook, "work", round, work, task)
+			start := time.Now()
+			elapsed, stopped := runPhase("Work "+roundLabel(round), work, interrupt)
+			focused += elapsed
+			if !stopped {
+				completed++
+			}
+			if task != nil && (!stopped || elapsed >= time.Second) {
+				p := pomodoro{Start: start, End: start.Add(elapsed.Round(time.Second)), Partial: stopped}
+				if err := recordPomodoro(task.ID, p); err != nil {
+					fmt.Println("Error recording pomodoro:", err)
+					os.Exit(1)
+				}
+			}
+			if stopped {
+				runFocusHook(hook, "stopped", round, elapsed, task)
+				fmt.Printf("Stopped after %s of work; %d pomodoro(s) completed.\n", formatCountdown(focused), completed)
+				return
+			}
+			if round == rounds {
+				break
+			}
+
+			phase, label, length := "break", "Break", shortBreak
+			if round%longEvery == 0 {
+				phase, label, length = "long-break", "Long break", longBreak
+			}
+			if length == 0 {
+				continue
+			}
+			runFocusHook(hook, phase, round, length, task)
+			if _, stopped := runPhase(label, length, interrupt); stopped {
+				runFocusHook(hook, "stopped", round, 0, task)
+				fmt.Printf("Stopped during the break; %d pomodoro(s) completed.\n", completed)
+				return
+			}
+		}
+
+		runFocusHook(hook, "done", rounds, focused, task)
+		fmt.Printf("Done: %d pomodoro(s), %s of focus.\n", completed, formatDuration(focused))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(focusCmd)
+	focusCmd.Flags().StringP("list", "L", "", "Todo list of the task (default: $PERSONALCLI_LIST or the saved default)")
+	focusCmd.Flags().Duration("work", 25*time.Minute, "Length of a work phase")
+	focusCmd.Flags().Duration("break", 5*time.Minute, "Length of a short break")
+	focusCmd.Flags().Duration("long-break", 15*time.Minute, "Length of a long break")
+	focusCmd.Flags().Int("long-every", 4, "Take a long break after this many rounds")
+	focusCmd.Flags().Int("rounds", 4, "Number of work phases to run (0 runs until Ctrl-C)")
+	focusCmd.Flags().String("hook", os.Getenv(focusHookEnvVar), "Shell command to run at every phase change")
+}
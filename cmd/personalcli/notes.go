@@ -3,6 +3,9 @@ package main
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
@@ -18,40 +21,86 @@ var notesCmd = &cobra.Command{
 	},
 }
 
+// noteHeader describes a note's ID, dates, flags and tags on one line.
+func noteHeader(note Note) string {
+	header := fmt.Sprintf("ID: %d | Date: %s", note.ID, note.CreatedAt.Format("2006-01-02 15:04"))
+	if note.UpdatedAt != nil {
+		header += " | Updated: " + note.UpdatedAt.Format("2006-01-02 15:04")
+	}
+	if note.Pinned {
+		header += " | Pinned"
+	}
+	if note.Archived {
+		header += " | Archived"
+	}
+	if len(note.Tags) > 0 {
+		header += " | Tags: #" + strings.Join(note.Tags, " #")
+	}
+	return header
+}
+
+// formatNote renders a note with its header, title and content.
+func formatNote(note Note) string {
+	lines := []string{noteHeader(note)}
+	if note.Title != "" {
+		lines = append(lines, note.Title)
+	}
+	if note.Content != "" {
+		lines = append(lines, note.Content)
+	}
+	return strings.Join(append(lines, "---"), "\n")
+}
+
 var noteNewCmd = &cobra.Command{
-	Use:   "new [note content]",
+	Use:   "new [note content | -]",
 	Short: "Create a new note",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Create a new note from the arguments. Without arguments the note is written in
+your editor ($VISUAL or $EDITOR), starting with a title line; with "-" (or
+when input is piped) it is read from stdin, e.g.
+
+  git log --oneline -5 | personalcli note new - --title "Recent commits"
+
+Words starting with # in the title or content, such as #work, tag the note.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		notes, err := readNotes()
+		title, content, err := readNoteText(args)
 		if err != nil {
-			fmt.Println("Error reading notes:", err)
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if titleFlag, _ := cmd.Flags().GetString("title"); titleFlag != "" {
+			title = titleFlag
+		}
+		if title == "" && content == "" {
+			fmt.Println("Aborting: the note is empty.")
 			os.Exit(1)
 		}
 
-		newID := 1
-		if len(notes) > 0 {
-			newID = notes[len(notes)-1].ID + 1
+		notes, err := readNotes()
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			os.Exit(1)
 		}
 
 		newNote := Note{
-			ID:        newID,
-			Content:   strings.Join(args, " "),
+			ID:        nextNoteID(notes),
 			CreatedAt: time.Now(),
 		}
+		newNote.setText(title, content)
 
 		notes = append(notes, newNote)
 		if err := writeNotes(notes); err != nil {
 			fmt.Println("Error writing notes:", err)
 			os.Exit(1)
 		}
-		fmt.Printf("Created note %d.\n", newNote.ID)
+		fmt.Printf("Created note %d: %s\n", newNote.ID, newNote.displayTitle())
 	},
 }
 
 var noteListCmd = &cobra.Command{
 	Use:   "list",
-	Short: "List all of your notes",
+	Short: "List your notes, pinned ones first",
+	Long: `List your notes with pinned notes first. Archived notes are hidden unless
+--all or --archived is given.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		notes, err := readNotes()
 		if err != nil {
@@ -64,36 +113,183 @@ var noteListCmd = &cobra.Command{
 			return
 		}
 
-		fmt.Println("Your notes:")
+		all, _ := cmd.Flags().GetBool("all")
+		archived, _ := cmd.Flags().GetBool("archived")
+		tag, _ := cmd.Flags().GetString("tag")
+		var visible []Note
 		for _, note := range notes {
-			fmt.Printf("ID: %d | Date: %s\n%s\n---\n", note.ID, note.CreatedAt.Format("2006-01-02 15:04"), note.Content)
+			switch {
+			case archived && !note.Archived, !archived && !all && note.Archived:
+				continue
+			case tag != "" && !note.hasTag(tag):
+				continue
+			}
+			visible = append(visible, note)
+		}
+		if len(visible) == 0 {
+			fmt.Println("No matching notes.")
+			return
+		}
+		sort.SliceStable(visible, func(i, j int) bool { return visible[i].Pinned && !visible[j].Pinned })
+
+		fmt.Println("Your notes:")
+		for _, note := range visible {
+			fmt.Println(formatNote(note))
 		}
 	},
 }
 
 var noteFindCmd = &cobra.Command{
-	Use:   "find [keyword]",
-	Short: "Find notes containing a keyword",
-	Args:  cobra.ExactArgs(1),
+	Use:   "find [query]",
+	Short: "Search notes, best matches first",
+	Long: `Search the title, content and tags of your notes. Results are ranked by
+relevance (BM25) and shown with the matching words highlighted.
+
+  meeting budget        notes containing both words
+  meeting OR standup    notes containing either word
+  meeting NOT budget    notes about meetings, leaving out budget
+  '"budget review"'     the words next to each other
+  meet*                 words starting with "meet"
+
+"-budget" also leaves a word out, after "--" so it is not taken for a flag:
+note find -- meeting -budget.
+
+With --regex the query is a regular expression (case-insensitive unless it
+starts with (?-i)); with --fuzzy each word also finds words with a typo or
+two, words starting with it and words containing its letters in order.
+--in limits the search to the title, content or tags, and --since/--until
+to notes created in that period. Archived notes are left out unless --all
+is given.`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		keyword := strings.ToLower(args[0])
+		queryText := strings.Join(args, " ")
+		useRegex, _ := cmd.Flags().GetBool("regex")
+		fuzzy, _ := cmd.Flags().GetBool("fuzzy")
+		if useRegex && fuzzy {
+			fmt.Println("Error: --regex and --fuzzy cannot be combined.")
+			os.Exit(1)
+		}
+		field, _ := cmd.Flags().GetString("in")
+		if field != "" && !slices.Contains(noteSearchFields, field) {
+			fmt.Printf("Error: invalid field %q (use %s)\n", field, strings.Join(noteSearchFields, ", "))
+			os.Exit(1)
+		}
+
+		now := currentTime()
+		var since, until time.Time
+		if value, _ := cmd.Flags().GetString("since"); value != "" {
+			t, err := parseSince(value, now)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			since = t
+		}
+		if value, _ := cmd.Flags().GetString("until"); value != "" {
+			t, err := parseSince(value, now)
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			// The until day itself is included, unless a time of day was given.
+			until = t
+			if t.Hour() == 0 && t.Minute() == 0 {
+				until = t.AddDate(0, 0, 1)
+			}
+		}
+
 		notes, err := readNotes()
 		if err != nil {
 			fmt.Println("Error reading notes:", err)
 			os.Exit(1)
 		}
+		all, _ := cmd.Flags().GetBool("all")
+		wanted := func(note Note) bool {
+			return (all || !note.Archived) &&
+				(since.IsZero() || !note.CreatedAt.Before(since)) &&
+				(until.IsZero() || note.CreatedAt.Before(until))
+		}
 
-		fmt.Printf("Searching for notes with keyword: \"%s\"\n", keyword)
-		found := false
-		for _, note := range notes {
-			if strings.Contains(strings.ToLower(note.Content), keyword) {
-				fmt.Printf("ID: %d | Date: %s\n%s\n---\n", note.ID, note.CreatedAt.Format("2006-01-02 15:04"), note.Content)
-				found = true
+		var matches []noteMatch
+		switch {
+		case useRegex:
+			re, err := regexp.Compile("(?i)" + queryText)
+			if err != nil {
+				fmt.Println("Error in regular expression:", err)
+				os.Exit(1)
+			}
+			for _, note := range notes {
+				if match, ok := regexMatch(re, note, field); ok && wanted(note) {
+					matches = append(matches, match)
+				}
+			}
+			sortMatches(matches)
+
+		case fuzzy:
+			matcher := newFuzzyMatcher(queryText)
+			if len(matcher.words) == 0 {
+				fmt.Println("Error in query: nothing to search for")
+				os.Exit(1)
+			}
+			for _, note := range notes {
+				if match, ok := matcher.match(note, field); ok && wanted(note) {
+					matches = append(matches, match)
+				}
+			}
+			sortMatches(matches)
+
+		default:
+			search, err := parseNoteSearch(queryText)
+			if err != nil {
+				fmt.Println("Error in query:", err)
+				os.Exit(1)
+			}
+			idx, err := syncNoteIndex(notes)
+			if err != nil {
+				fmt.Println("Warning: could not save the search index:", err)
+			}
+			indexField := field
+			if field == "tags" {
+				idx, indexField = tagIndex(notes), ""
+			}
+			for _, result := range search.run(idx, indexField) {
+				note := notes[findNote(notes, result.id)]
+				if !wanted(note) {
+					continue
+				}
+				matches = append(matches, noteMatch{
+					note:         note,
+					score:        result.score,
+					titleSpans:   termSpans(note.Title, search.highlights),
+					contentSpans: termSpans(note.Content, search.highlights),
+				})
 			}
 		}
 
-		if !found {
+		if len(matches) == 0 {
 			fmt.Println("No matching notes found.")
+			return
+		}
+
+		mark := func(word string) string { return "**" + word + "**" }
+		if isTerminal(os.Stdout) {
+			mark = func(word string) string { return "\033[1;33m" + word + "\033[0m" }
+		}
+		limit, _ := cmd.Flags().GetInt("limit")
+		fmt.Printf("Found %d note(s) matching %s:\n", len(matches), queryText)
+		for i, match := range matches {
+			if limit > 0 && i == limit {
+				fmt.Printf("... and %d more (use --limit 0 to see all)\n", len(matches)-limit)
+				break
+			}
+			fmt.Println(noteHeader(match.note))
+			if match.note.Title != "" {
+				fmt.Println(snippet(match.note.Title, match.titleSpans, mark))
+			}
+			if match.note.Content != "" {
+				fmt.Println(snippet(match.note.Content, match.contentSpans, mark))
+			}
+			fmt.Println("---")
 		}
 	},
 }
@@ -103,4 +299,15 @@ func init() {
 	notesCmd.AddCommand(noteNewCmd)
 	notesCmd.AddCommand(noteListCmd)
 	notesCmd.AddCommand(noteFindCmd)
+	noteNewCmd.Flags().String("title", "", "Title of the note")
+	noteListCmd.Flags().Bool("all", false, "Include archived notes")
+	noteListCmd.Flags().Bool("archived", false, "Show only archived notes")
+	noteListCmd.Flags().String("tag", "", "Show only notes with this tag")
+	noteFindCmd.Flags().Bool("all", false, "Include archived notes")
+	noteFindCmd.Flags().IntP("limit", "n", 20, "Show at most this many results (0 for all)")
+	noteFindCmd.Flags().Bool("regex", false, "Treat the query as a regular expression")
+	noteFindCmd.Flags().Bool("fuzzy", false, "Also match misspelled and partial words")
+	noteFindCmd.Flags().String("in", "", "Search only the title, content or tags")
+	noteFindCmd.Flags().String("since", "", "Only notes created on or after: today, a weekday, Nd or YYYY-MM-DD")
+	noteFindCmd.Flags().String("until", "", "Only notes created on or before: today, a weekday, Nd or YYYY-MM-DD")
 }
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// editorCommand returns the user's editor: $VISUAL, then $EDITOR, then vi.
+func editorCommand() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(name)); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+// editText opens text in the user's editor and returns what was saved. The
+// editor runs through the shell so settings such as "code --wait" work.
+func editText(text string) (string, error) {
+	file, err := os.CreateTemp("", "personalcli-note-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	editor := editorCommand()
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", file.Name())
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running %s: %v", editor, err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// noteEditorText lays a note out for editing: the title on the first line,
+// then a blank line and the content.
+func noteEditorText(note Note) string {
+	return note.Title + "\n\n" + note.Content + "\n"
+}
+
+// newNoteTemplate is the text "note new" opens the editor with: an empty
+// title line to fill in, then room for the content.
+const newNoteTemplate = "# \n\n"
+
+// parseNoteEditorText splits edited text into a title (the first line, with
+// any Markdown heading marker removed) and the content after it.
+func parseNoteEditorText(text string) (title, content string) {
+	first, rest, _ := strings.Cut(text, "\n")
+	title = strings.TrimSpace(first)
+	if title == "#" {
+		title = ""
+	}
+	title = strings.TrimSpace(strings.TrimPrefix(title, "# "))
+	return title, trimBlankLines(rest)
+}
+
+// trimBlankLines removes the blank lines before and the whitespace after
+// text, keeping the indentation of its first line, e.g. in a code block.
+func trimBlankLines(text string) string {
+	text = strings.TrimRight(text, " \t\r\n")
+	for {
+		line, rest, found := strings.Cut(text, "\n")
+		if !found || strings.TrimSpace(line) != "" {
+			break
+		}
+		text = rest
+	}
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	return text
+}
+
+// readNoteText reads the title and content of a new note: the content from
+// the arguments, from stdin when the only argument is "-" or input is piped,
+// and otherwise both from the editor, where the first line is the title.
+func readNoteText(args []string) (title, content string, err error) {
+	switch {
+	case len(args) == 1 && args[0] == "-", len(args) == 0 && !isTerminal(os.Stdin):
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", "", err
+		}
+		return "", trimBlankLines(string(data)), nil
+	case len(args) > 0:
+		return "", strings.Join(args, " "), nil
+	}
+	text, err := editText(newNoteTemplate)
+	if err != nil {
+		return "", "", err
+	}
+	title, content = parseNoteEditorText(text)
+	return title, content, nil
+}
+
+var noteEditCmd = &cobra.Command{
+	Use:   "edit [note_id]",
+	Short: "Edit a note in your editor ($VISUAL or $EDITOR)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		noteID, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Println("Invalid note ID. Please provide a number.")
+			os.Exit(1)
+		}
+
+		notes, err := readNotes()
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			os.Exit(1)
+		}
+		noteIndex := findNote(notes, noteID)
+		if noteIndex == -1 {
+			fmt.Println("Note ID not found.")
+			os.Exit(1)
+		}
+
+		note := &notes[noteIndex]
+		text, err := editText(noteEditorText(*note))
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		title, content := parseNoteEditorText(text)
+		switch {
+		case title == "" && content == "":
+			fmt.Println("Empty note; keeping the old content.")
+			return
+		case title == note.Title && content == note.Content:
+			fmt.Printf("No changes to note %d.\n", noteID)
+			return
+		}
+
+		now := time.Now()
+		note.setText(title, content)
+		note.UpdatedAt = &now
+		if err := writeNotes(notes); err != nil {
+			fmt.Println("Error writing notes:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Updated note %d.\n", noteID)
+	},
+}
+
+func init() {
+	notesCmd.AddCommand(noteEditCmd)
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestParseNoteEditorText(t *testing.T) {
+	for _, tc := range []struct {
+		text, title, content string
+	}{
+		{newNoteTemplate, "", ""},
+		{"# Plan\n\nFirst step\n", "Plan", "First step"},
+		{"Plan\nFirst step", "Plan", "First step"},
+		{"#\n\nJust content\n", "", "Just content"},
+		{"#work plan\n\nbody", "#work plan", "body"},
+		// Indentation of the first content line is kept.
+		{"# Snippet\n\n\n    go test ./...\n    go vet ./...\n\n", "Snippet", "    go test ./...\n    go vet ./..."},
+		{"# Title only\n\n  \n", "Title only", ""},
+	} {
+		title, content := parseNoteEditorText(tc.text)
+		if title != tc.title || content != tc.content {
+			t.Errorf("parseNoteEditorText(%q) = %q, %q, want %q, %q", tc.text, title, content, tc.title, tc.content)
+		}
+	}
+}
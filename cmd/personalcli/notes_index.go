@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// noteIndexVersion changes whenever the index format or tokenizer does, so
+// old indexes are rebuilt instead of misread.
+const noteIndexVersion = 3
+
+// BM25 parameters: k1 limits how much repeating a term counts, b how much
+// long notes are penalized.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// noteIndex is an inverted index over the title and content of every note.
+// It is assembled in memory from the segments kept on disk.
+type noteIndex struct {
+	Notes map[int]indexedNote
+	// Postings maps each term to the notes containing it and the positions
+	// of the term in each note.
+	Postings map[string]map[int][]int
+}
+
+// indexedNote is what the index knows about one note.
+type indexedNote struct {
+	// Length counts the note's tokens; the first TitleLength are the title.
+	Length      int
+	TitleLength int
+	Terms       []string
+}
+
+// noteSegment is the index of a single note. Segments are named after the
+// note's ID and the hash of its text, so a changed note is spotted without
+// reading its segment. Most live together in one base file; notes added or
+// changed since it was written get a small file of their own until there
+// are enough of those to merge them into the base.
+type noteSegment struct {
+	Length      int              `json:"length"`
+	TitleLength int              `json:"title_length"`
+	Postings    map[string][]int `json:"postings"`
+}
+
+// noteIndexMeta records which note store the segments were built from and
+// which segments the base file holds.
+type noteIndexMeta struct {
+	Version  int      `json:"version"`
+	Location string   `json:"location"`
+	Base     []string `json:"base,omitempty"`
+}
+
+// noteIndexMaxPending is how many segments may sit outside the base, or be
+// left in it for notes that changed since, before the base is rewritten.
+const noteIndexMaxPending = 32
+
+// noteToken is a word in a note: its lowercased term and byte offsets.
+type noteToken struct {
+	term       string
+	start, end int
+}
+
+// tokenizeNote splits text into lowercased runs of letters and digits.
+func tokenizeNote(text string) []noteToken {
+	var tokens []noteToken
+	start := -1
+	for i, r := range text {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case word && start == -1:
+			start = i
+		case !word && start != -1:
+			tokens = append(tokens, noteToken{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start != -1 {
+		tokens = append(tokens, noteToken{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+// noteIndexDirPath returns the directory the notes index is kept in.
+func noteIndexDirPath() string {
+	return filepath.Join(notesConfigDir, "notes.index")
+}
+
+// newNoteIndex returns an empty index.
+func newNoteIndex() *noteIndex {
+	return &noteIndex{Notes: map[int]indexedNote{}, Postings: map[string]map[int][]int{}}
+}
+
+// noteHash fingerprints the indexed text of a note.
+func noteHash(note Note) string {
+	h := fnv.New64a()
+	h.Write([]byte(note.Title + "\x00" + note.Content))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// segmentFileName returns the name of the segment indexing a note.
+func segmentFileName(note Note) string {
+	return fmt.Sprintf("%d-%s.json", note.ID, noteHash(note))
+}
+
+// indexNote tokenizes a note's title and content into a segment.
+func indexNote(note Note) noteSegment {
+	titleTokens := tokenizeNote(note.Title)
+	tokens := append(titleTokens, tokenizeNote(note.Content)...)
+	seg := noteSegment{Length: len(tokens), TitleLength: len(titleTokens), Postings: map[string][]int{}}
+	for pos, tok := range tokens {
+		seg.Postings[tok.term] = append(seg.Postings[tok.term], pos)
+	}
+	return seg
+}
+
+// addSegment adds the segment of a note to the index.
+func (idx *noteIndex) addSegment(id int, seg noteSegment) {
+	entry := indexedNote{Length: seg.Length, TitleLength: seg.TitleLength}
+	for term, positions := range seg.Postings {
+		postings := idx.Postings[term]
+		if postings == nil {
+			postings = map[int][]int{}
+			idx.Postings[term] = postings
+		}
+		postings[id] = positions
+		entry.Terms = append(entry.Terms, term)
+	}
+	idx.Notes[id] = entry
+}
+
+// add indexes a note's title and content.
+func (idx *noteIndex) add(note Note) {
+	idx.addSegment(note.ID, indexNote(note))
+}
+
+// openNoteIndex reads the index metadata and the names of the segment files
+// outside the base, emptying the index directory first when it was built
+// from another note store or by an older version.
+func openNoteIndex(location string) (noteIndexMeta, map[string]bool, error) {
+	dir := noteIndexDirPath()
+	want := noteIndexMeta{Version: noteIndexVersion, Location: location}
+	var meta noteIndexMeta
+	data, err := os.ReadFile(filepath.Join(dir, "meta.json"))
+	if err != nil || json.Unmarshal(data, &meta) != nil || meta.Version != want.Version || meta.Location != want.Location {
+		if err := os.RemoveAll(dir); err != nil {
+			return want, nil, err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return want, nil, err
+		}
+		return want, map[string]bool{}, writeJSONFile(filepath.Join(dir, "meta.json"), want)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return meta, nil, err
+	}
+	segments := map[string]bool{}
+	for _, entry := range entries {
+		if name := entry.Name(); name != "meta.json" && name != "base.json" {
+			segments[name] = true
+		}
+	}
+	return meta, segments, nil
+}
+
+// writeJSONFile writes v to path as JSON, replacing the file in one step so
+// a reader never sees half of it.
+func writeJSONFile(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readSegment reads a segment file, reporting whether it was usable.
+func readSegment(path string, seg any) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && json.Unmarshal(data, seg) == nil
+}
+
+// updateNoteIndex brings the segments on disk in line with notes: notes that
+// were added or changed get a segment file of their own, and those of
+// changed or deleted notes are removed. Unchanged notes are not touched.
+// With load set, it also returns the whole index, merging the separate
+// segments into the base once there are more than noteIndexMaxPending.
+func updateNoteIndex(notes []Note, load bool) (*noteIndex, error) {
+	idx := newNoteIndex()
+	store, err := currentNoteStore()
+	if err != nil {
+		return idx, err
+	}
+	meta, separate, err := openNoteIndex(store.location())
+	if err != nil {
+		// Without a usable index directory, index everything in memory.
+		for _, note := range notes {
+			idx.add(note)
+		}
+		return idx, err
+	}
+
+	dir := noteIndexDirPath()
+	inBase := map[string]bool{}
+	for _, name := range meta.Base {
+		inBase[name] = true
+	}
+	base := map[string]noteSegment{}
+	if load && len(meta.Base) > 0 {
+		readSegment(filepath.Join(dir, "base.json"), &base)
+	}
+
+	var firstErr error
+	keep := func(err error) {
+		if firstErr == nil && err != nil {
+			firstErr = err
+		}
+	}
+	segments := map[string]noteSegment{}
+	var pending []string
+	for _, note := range notes {
+		name := segmentFileName(note)
+		if inBase[name] {
+			delete(inBase, name)
+			if !load {
+				continue
+			}
+			if seg, ok := base[name]; ok {
+				idx.addSegment(note.ID, seg)
+				segments[name] = seg
+				continue
+			}
+		}
+		pending = append(pending, name)
+		if separate[name] {
+			delete(separate, name)
+			if !load {
+				continue
+			}
+			var seg noteSegment
+			if readSegment(filepath.Join(dir, name), &seg) {
+				idx.addSegment(note.ID, seg)
+				segments[name] = seg
+				continue
+			}
+		}
+		seg := indexNote(note)
+		idx.addSegment(note.ID, seg)
+		segments[name] = seg
+		keep(writeJSONFile(filepath.Join(dir, name), seg))
+	}
+	// Whatever separate segments are left belong to notes that changed or
+	// are gone.
+	for name := range separate {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			keep(err)
+		}
+	}
+
+	// What is left in inBase is stale too, but stays until the next merge.
+	if load && len(pending)+len(inBase) > noteIndexMaxPending && firstErr == nil {
+		keep(mergeNoteIndex(meta, segments, pending))
+	}
+	return idx, firstErr
+}
+
+// mergeNoteIndex rewrites the base to hold exactly the given segments and
+// removes the separate segment files it now includes.
+func mergeNoteIndex(meta noteIndexMeta, segments map[string]noteSegment, separate []string) error {
+	dir := noteIndexDirPath()
+	if err := writeJSONFile(filepath.Join(dir, "base.json"), segments); err != nil {
+		return err
+	}
+	meta.Base = meta.Base[:0]
+	for name := range segments {
+		meta.Base = append(meta.Base, name)
+	}
+	sort.Strings(meta.Base)
+	if err := writeJSONFile(filepath.Join(dir, "meta.json"), meta); err != nil {
+		return err
+	}
+	for _, name := range separate {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncNoteIndex brings the index up to date with notes and returns it.
+func syncNoteIndex(notes []Note) (*noteIndex, error) {
+	return updateNoteIndex(notes, true)
+}
+
+// averageLength returns the mean number of tokens per note.
+func (idx *noteIndex) averageLength() float64 {
+	if len(idx.Notes) == 0 {
+		return 0
+	}
+	total := 0
+	for _, entry := range idx.Notes {
+		total += entry.Length
+	}
+	return float64(total) / float64(len(idx.Notes))
+}
+
+// bm25 scores a term occurring tf times in a note, given how many notes
+// contain the term and the average note length.
+func (idx *noteIndex) bm25(id, tf, df int, avgLength float64) float64 {
+	n := float64(len(idx.Notes))
+	idf := math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+	norm := 1 - bm25B + bm25B*float64(idx.Notes[id].Length)/avgLength
+	return idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+}
@@ -5,16 +5,89 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 )
 
 // Note represents a single note item.
 type Note struct {
-	ID        int       `json:"id"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int        `json:"id"`
+	Title     string     `json:"title,omitempty"`
+	Content   string     `json:"content"`
+	Tags      []string   `json:"tags,omitempty"`
+	Pinned    bool       `json:"pinned,omitempty"`
+	Archived  bool       `json:"archived,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
 }
 
+// noteTagPattern matches a "#tag" in note text. Tags start with a letter, so
+// "issue #12" and Markdown headings ("# Title") are not tags.
+var noteTagPattern = regexp.MustCompile(`(?:^|[\s(])#(\p{L}[\p{L}\p{N}_/-]*)`)
+
+// parseNoteTags returns the distinct "#tags" in text, lowercased and sorted.
+func parseNoteTags(text string) []string {
+	var tags []string
+	for _, m := range noteTagPattern.FindAllStringSubmatch(text, -1) {
+		tag := strings.ToLower(strings.TrimRight(m[1], "/-"))
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// setText sets a note's title and content and refreshes its tags.
+func (n *Note) setText(title, content string) {
+	n.Title = title
+	n.Content = content
+	n.Tags = parseNoteTags(title + "\n" + content)
+}
+
+// displayTitle returns the note's title, or the start of its first line for
+// notes without one.
+func (n Note) displayTitle() string {
+	if n.Title != "" {
+		return n.Title
+	}
+	first, _, _ := strings.Cut(n.Content, "\n")
+	if runes := []rune(first); len(runes) > 60 {
+		return string(runes[:59]) + "…"
+	}
+	return first
+}
+
+// hasTag reports whether the note carries the given tag.
+func (n Note) hasTag(tag string) bool {
+	return slices.Contains(n.Tags, strings.ToLower(strings.TrimPrefix(tag, "#")))
+}
+
+// nextNoteID returns the ID for a new note.
+func nextNoteID(notes []Note) int {
+	id := 0
+	for _, note := range notes {
+		id = max(id, note.ID)
+	}
+	return id + 1
+}
+
+// findNote returns the index of the note with the given ID, or -1.
+func findNote(notes []Note, id int) int {
+	for i, note := range notes {
+		if note.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// notesConfigDir holds notes.json and the notes storage setting.
+var notesConfigDir string
+
 // notesFilePath is the path to the JSON file where notes are stored.
 var notesFilePath string
 
@@ -27,19 +100,31 @@ func init() {
 	}
 
 	// Define the path for the notes file
-	configDir := filepath.Join(home, ".config", "personalcli")
-	notesFilePath = filepath.Join(configDir, "notes.json")
+	notesConfigDir = filepath.Join(home, ".config", "personalcli")
+	notesFilePath = filepath.Join(notesConfigDir, "notes.json")
 
 	// Create the directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(notesConfigDir, 0755); err != nil {
 		fmt.Println("Error creating config directory:", err)
 		os.Exit(1)
 	}
 }
 
-// readNotes reads all notes from the notes.json file.
-func readNotes() ([]Note, error) {
-	data, err := os.ReadFile(notesFilePath)
+// noteStore keeps the notes somewhere: in notes.json or as Markdown files.
+type noteStore interface {
+	read() ([]Note, error)
+	write(notes []Note) error
+	// location describes where the notes are kept, for messages.
+	location() string
+}
+
+// jsonNoteStore keeps all notes in one JSON array.
+type jsonNoteStore struct {
+	path string
+}
+
+func (s *jsonNoteStore) read() ([]Note, error) {
+	data, err := os.ReadFile(s.path)
 	if err != nil {
 		// If the file doesn't exist, return an empty list of notes.
 		if os.IsNotExist(err) {
@@ -56,11 +141,69 @@ func readNotes() ([]Note, error) {
 	return notes, nil
 }
 
-// writeNotes writes a list of notes to the notes.json file.
-func writeNotes(notes []Note) error {
+func (s *jsonNoteStore) write(notes []Note) error {
 	data, err := json.MarshalIndent(notes, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(notesFilePath, data, 0644)
+	return os.WriteFile(s.path, data, 0644)
+}
+
+func (s *jsonNoteStore) location() string {
+	return s.path
+}
+
+// activeNoteStore is the store readNotes and writeNotes use, picked on
+// first use.
+var activeNoteStore noteStore
+
+// currentNoteStore returns the configured note store.
+func currentNoteStore() (noteStore, error) {
+	if activeNoteStore == nil {
+		dir, err := configuredNotesDir()
+		if err != nil {
+			return nil, err
+		}
+		if dir == "" {
+			activeNoteStore = &jsonNoteStore{path: notesFilePath}
+		} else {
+			activeNoteStore = &markdownNoteStore{dir: dir}
+		}
+	}
+	return activeNoteStore, nil
+}
+
+// readNotes reads all notes from the configured store.
+func readNotes() ([]Note, error) {
+	store, err := currentNoteStore()
+	if err != nil {
+		return nil, err
+	}
+	notes, err := store.read()
+	if err != nil {
+		return nil, err
+	}
+	// Tags always follow the text, which may have been edited outside
+	// personalcli; stored tags are only a copy for other tools.
+	for i := range notes {
+		notes[i].setText(notes[i].Title, notes[i].Content)
+	}
+	return notes, nil
+}
+
+// writeNotes writes a list of notes to the configured store.
+func writeNotes(notes []Note) error {
+	store, err := currentNoteStore()
+	if err != nil {
+		return err
+	}
+	if err := store.write(notes); err != nil {
+		return err
+	}
+	// Only changed notes are re-indexed. The index is a cache: "note find"
+	// rebuilds whatever is missing.
+	if _, err := updateNoteIndex(notes, false); err != nil {
+		fmt.Println("Warning: could not update the search index:", err)
+	}
+	return nil
 }
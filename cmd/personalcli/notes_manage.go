@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// changeNotes applies change to every note named in args and saves the notes
+// once. change returns the message to print for the note, whether the note
+// was changed and whether it should be deleted. Unknown IDs are reported
+// individually and make the command fail after the others have been saved.
+func changeNotes(args []string, change func(note *Note) (message string, changed, remove bool)) {
+	notes, err := readNotes()
+	if err != nil {
+		fmt.Println("Error reading notes:", err)
+		os.Exit(1)
+	}
+
+	changed, failed := false, false
+	removed := map[int]bool{}
+	for _, arg := range args {
+		noteID, err := strconv.Atoi(arg)
+		if err != nil {
+			fmt.Printf("Invalid note ID %q. Please provide a number.\n", arg)
+			failed = true
+			continue
+		}
+		noteIndex := findNote(notes, noteID)
+		if noteIndex == -1 {
+			fmt.Printf("Note %d not found.\n", noteID)
+			failed = true
+			continue
+		}
+		message, ok, remove := change(&notes[noteIndex])
+		fmt.Println(message)
+		changed = changed || ok || remove
+		if remove {
+			removed[noteID] = true
+		}
+	}
+
+	if changed {
+		notes = slices.DeleteFunc(notes, func(note Note) bool { return removed[note.ID] })
+		if err := writeNotes(notes); err != nil {
+			fmt.Println("Error writing notes:", err)
+			os.Exit(1)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// noteFlagCmd builds a command that sets or clears a note flag, such as
+// "pin" and "unpin".
+func noteFlagCmd(use, short, verb, state string, flag func(note *Note) *bool, value bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " [note_id...]",
+		Short: short,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			changeNotes(args, func(note *Note) (string, bool, bool) {
+				field := flag(note)
+				if *field == value {
+					return fmt.Sprintf("Note %d is already %s.", note.ID, state), false, false
+				}
+				*field = value
+				return fmt.Sprintf("%s note %d: %s", verb, note.ID, note.displayTitle()), true, false
+			})
+		},
+	}
+}
+
+func notePinned(note *Note) *bool   { return &note.Pinned }
+func noteArchived(note *Note) *bool { return &note.Archived }
+
+var noteRmCmd = &cobra.Command{
+	Use:   "rm [note_id...]",
+	Short: "Delete notes",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		changeNotes(args, func(note *Note) (string, bool, bool) {
+			return fmt.Sprintf("Deleted note %d: %s", note.ID, note.displayTitle()), false, true
+		})
+	},
+}
+
+var noteTagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "Summarize note counts per tag",
+	Run: func(cmd *cobra.Command, args []string) {
+		notes, err := readNotes()
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			os.Exit(1)
+		}
+
+		all, _ := cmd.Flags().GetBool("all")
+		counts := map[string]int{}
+		for _, note := range notes {
+			if note.Archived && !all {
+				continue
+			}
+			for _, tag := range note.Tags {
+				counts[tag]++
+			}
+		}
+
+		if len(counts) == 0 {
+			fmt.Println("No tags in use. Tag a note by writing #tag in it.")
+			return
+		}
+		fmt.Println("Tags:")
+		for _, tag := range sortedKeys(counts) {
+			fmt.Printf("  #%s: %d\n", tag, counts[tag])
+		}
+	},
+}
+
+func init() {
+	notesCmd.AddCommand(noteRmCmd)
+	notesCmd.AddCommand(noteFlagCmd("pin", "Pin notes to the top of the list", "Pinned", "pinned", notePinned, true))
+	notesCmd.AddCommand(noteFlagCmd("unpin", "Unpin notes", "Unpinned", "unpinned", notePinned, false))
+	notesCmd.AddCommand(noteFlagCmd("archive", "Archive notes, hiding them from the list", "Archived", "archived", noteArchived, true))
+	notesCmd.AddCommand(noteFlagCmd("unarchive", "Bring archived notes back", "Unarchived", "not archived", noteArchived, false))
+	notesCmd.AddCommand(noteTagsCmd)
+	noteTagsCmd.Flags().Bool("all", false, "Include archived notes")
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// notesDirEnvVar stores notes as Markdown files in the given directory,
+// overriding the setting saved with "note storage".
+const notesDirEnvVar = "PERSONALCLI_NOTES_DIR"
+
+// notesDirFilePath returns the file recording the Markdown notes directory.
+// Without it notes are kept in notes.json.
+func notesDirFilePath() string {
+	return filepath.Join(notesConfigDir, "notes_dir")
+}
+
+// defaultMarkdownNotesDir is where "note storage markdown" puts the files
+// unless told otherwise.
+func defaultMarkdownNotesDir() string {
+	return filepath.Join(notesConfigDir, "notes")
+}
+
+// configuredNotesDir returns the directory of Markdown notes, or "" when
+// notes are kept in notes.json: $PERSONALCLI_NOTES_DIR, then the directory
+// saved with "note storage markdown".
+func configuredNotesDir() (string, error) {
+	if dir := os.Getenv(notesDirEnvVar); dir != "" {
+		return dir, nil
+	}
+	data, err := os.ReadFile(notesDirFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// noteFrontMatter is the YAML header of a Markdown note.
+type noteFrontMatter struct {
+	ID        int        `yaml:"id"`
+	Title     string     `yaml:"title,omitempty"`
+	CreatedAt time.Time  `yaml:"created_at"`
+	UpdatedAt *time.Time `yaml:"updated_at,omitempty"`
+	Tags      []string   `yaml:"tags,omitempty"`
+	Pinned    bool       `yaml:"pinned,omitempty"`
+	Archived  bool       `yaml:"archived,omitempty"`
+}
+
+// markdownFile is a note file as last read or written.
+type markdownFile struct {
+	path string
+	data []byte
+}
+
+// markdownNoteStore keeps each note in its own Markdown file with YAML front
+// matter, named after its ID and title. Only files whose note changed are
+// rewritten.
+type markdownNoteStore struct {
+	dir   string
+	files map[int]markdownFile
+}
+
+// markdownNoteFileName returns the file name for a note, e.g. "12-roadmap.md".
+func markdownNoteFileName(note Note) string {
+	words := strings.FieldsFunc(strings.ToLower(note.displayTitle()), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	slug := ""
+	for _, word := range words {
+		if len(slug)+len(word) > 40 {
+			break
+		}
+		slug += "-" + word
+	}
+	return fmt.Sprintf("%d%s.md", note.ID, slug)
+}
+
+// renderMarkdownNote writes a note as front matter followed by its content.
+func renderMarkdownNote(note Note) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	err := enc.Encode(noteFrontMatter{
+		ID:        note.ID,
+		Title:     note.Title,
+		CreatedAt: note.CreatedAt,
+		UpdatedAt: note.UpdatedAt,
+		Tags:      note.Tags,
+		Pinned:    note.Pinned,
+		Archived:  note.Archived,
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteString("---\n")
+	if note.Content != "" {
+		buf.WriteString("\n" + note.Content + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// parseMarkdownNote reads a note file. Files written by other tools may lack
+// front matter or some of its fields: the ID is then left 0, a missing
+// creation time is taken from modified, and a leading "# heading" becomes
+// the title.
+func parseMarkdownNote(data []byte, modified time.Time) (Note, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	var front noteFrontMatter
+	if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+		header, body, found := strings.Cut(rest, "\n---\n")
+		if !found {
+			header, found = strings.CutSuffix(rest, "\n---")
+		}
+		if found {
+			if err := yaml.Unmarshal([]byte(header), &front); err != nil {
+				return Note{}, fmt.Errorf("invalid front matter: %v", err)
+			}
+			text = body
+		}
+	}
+
+	note := Note{
+		ID:        front.ID,
+		Title:     front.Title,
+		Content:   trimBlankLines(text),
+		Tags:      front.Tags,
+		Pinned:    front.Pinned,
+		Archived:  front.Archived,
+		CreatedAt: front.CreatedAt,
+		UpdatedAt: front.UpdatedAt,
+	}
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = modified
+	}
+	if note.Title == "" {
+		first, rest, _ := strings.Cut(note.Content, "\n")
+		if title, ok := strings.CutPrefix(first, "# "); ok {
+			note.Title = strings.TrimSpace(title)
+			note.Content = trimBlankLines(rest)
+		}
+	}
+	return note, nil
+}
+
+func (s *markdownNoteStore) read() ([]Note, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	s.files = map[int]markdownFile{}
+	notes := []Note{}
+	var unnumbered []Note
+	var unnumberedPaths []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		note, err := parseMarkdownNote(data, info.ModTime())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if _, taken := s.files[note.ID]; note.ID <= 0 || taken {
+			unnumbered = append(unnumbered, note)
+			unnumberedPaths = append(unnumberedPaths, path)
+			continue
+		}
+		s.files[note.ID] = markdownFile{path, data}
+		notes = append(notes, note)
+	}
+
+	// Files added by hand, or copies of another note, get a new ID. Their
+	// files are renamed to match the next time the notes are written.
+	for i, note := range unnumbered {
+		note.ID = nextNoteID(notes)
+		s.files[note.ID] = markdownFile{path: unnumberedPaths[i]}
+		notes = append(notes, note)
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+	return notes, nil
+}
+
+func (s *markdownNoteStore) write(notes []Note) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	if s.files == nil {
+		s.files = map[int]markdownFile{}
+	}
+
+	// A file is only removed when no note is about to use its name.
+	kept := map[int]bool{}
+	paths := map[string]bool{}
+	for _, note := range notes {
+		kept[note.ID] = true
+		paths[filepath.Join(s.dir, markdownNoteFileName(note))] = true
+	}
+
+	for _, note := range notes {
+		data, err := renderMarkdownNote(note)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(s.dir, markdownNoteFileName(note))
+		old, known := s.files[note.ID]
+		if known && old.path == path && bytes.Equal(old.data, data) {
+			continue
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+		// A new title renames the file.
+		if known && old.path != path && !paths[old.path] {
+			if err := os.Remove(old.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		s.files[note.ID] = markdownFile{path, data}
+	}
+
+	// Remove the files of deleted notes.
+	for id, file := range s.files {
+		if kept[id] {
+			continue
+		}
+		if !paths[file.path] {
+			if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		delete(s.files, id)
+	}
+	return nil
+}
+
+func (s *markdownNoteStore) location() string {
+	return s.dir
+}
+
+var noteStorageCmd = &cobra.Command{
+	Use:   "storage [json|markdown]",
+	Short: "Show or change how notes are stored",
+	Long: `Show where notes are stored, or move them to another storage mode:
+
+  json      all notes in one file, notes.json (the default)
+  markdown  one Markdown file per note with YAML front matter, in
+            ~/.config/personalcli/notes or the directory given with --dir
+
+Switching copies every note to the new storage. A notes.json left behind is
+kept as notes.json.bak; Markdown files left behind stay where they are.
+$PERSONALCLI_NOTES_DIR, when set, overrides the saved mode.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"json", "markdown"},
+	Run: func(cmd *cobra.Command, args []string) {
+		current, err := currentNoteStore()
+		if err != nil {
+			fmt.Println("Error reading notes storage setting:", err)
+			os.Exit(1)
+		}
+		if len(args) == 0 {
+			mode := "json"
+			if _, ok := current.(*markdownNoteStore); ok {
+				mode = "markdown"
+			}
+			fmt.Printf("Notes are stored as %s in %s\n", mode, current.location())
+			return
+		}
+
+		var target noteStore
+		switch args[0] {
+		case "json":
+			target = &jsonNoteStore{path: notesFilePath}
+		case "markdown":
+			dir, _ := cmd.Flags().GetString("dir")
+			if dir == "" {
+				dir = defaultMarkdownNotesDir()
+			}
+			if dir, err = filepath.Abs(dir); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			target = &markdownNoteStore{dir: dir}
+		default:
+			fmt.Printf("Error: invalid storage %q (use json or markdown)\n", args[0])
+			os.Exit(1)
+		}
+		if target.location() == current.location() {
+			fmt.Printf("Notes are already stored in %s\n", current.location())
+			return
+		}
+
+		notes, err := readNotes()
+		if err != nil {
+			fmt.Println("Error reading notes:", err)
+			os.Exit(1)
+		}
+		existing, err := target.read()
+		if err != nil {
+			fmt.Println("Error reading", target.location()+":", err)
+			os.Exit(1)
+		}
+		if len(existing) > 0 {
+			fmt.Printf("Error: %s already holds %d note(s); move them away first.\n", target.location(), len(existing))
+			os.Exit(1)
+		}
+		if err := target.write(notes); err != nil {
+			fmt.Println("Error writing notes:", err)
+			os.Exit(1)
+		}
+
+		if markdown, ok := target.(*markdownNoteStore); ok {
+			err = os.WriteFile(notesDirFilePath(), []byte(markdown.dir+"\n"), 0644)
+		} else if err = os.Remove(notesDirFilePath()); os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil {
+			fmt.Println("Error saving notes storage setting:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Moved %d note(s) to %s.\n", len(notes), target.location())
+		if old, ok := current.(*jsonNoteStore); ok {
+			if err := os.Rename(old.path, old.path+".bak"); err == nil {
+				fmt.Printf("The old notes file is kept as %s.bak\n", old.path)
+			} else if !os.IsNotExist(err) {
+				fmt.Println("Warning: could not rename the old notes file:", err)
+			}
+		}
+		if os.Getenv(notesDirEnvVar) != "" {
+			fmt.Printf("Note: $%s is set and still takes precedence.\n", notesDirEnvVar)
+		}
+	},
+}
+
+func init() {
+	notesCmd.AddCommand(noteStorageCmd)
+	noteStorageCmd.Flags().String("dir", "", "Directory for Markdown notes (default: ~/.config/personalcli/notes)")
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarkdownNoteRoundTrip(t *testing.T) {
+	created := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+	updated := created.Add(48 * time.Hour)
+	for _, note := range []Note{
+		{ID: 1, Content: "Just a line", CreatedAt: created},
+		{ID: 2, Title: "Re: plan", Content: "First\n\nSecond paragraph", CreatedAt: created, UpdatedAt: &updated},
+		{ID: 3, Title: "Tagged", Content: "about #work and #home", Tags: []string{"home", "work"}, CreatedAt: created},
+		{ID: 4, Title: "Flags", Content: "x", Pinned: true, Archived: true, CreatedAt: created},
+		{ID: 5, Title: "Snippet", Content: "    go test ./...\n    go vet ./...", CreatedAt: created},
+		{ID: 6, Title: "Rules", Content: "above\n---\nbelow", CreatedAt: created},
+		{ID: 7, Title: "# not a heading", Content: "Café ☕", CreatedAt: created},
+		{ID: 8, Title: "Title only", CreatedAt: created},
+	} {
+		data, err := renderMarkdownNote(note)
+		if err != nil {
+			t.Fatalf("renderMarkdownNote(%d): %v", note.ID, err)
+		}
+		got, err := parseMarkdownNote(data, time.Now())
+		if err != nil {
+			t.Fatalf("parseMarkdownNote(%d): %v\n%s", note.ID, err, data)
+		}
+		if !reflect.DeepEqual(got, note) {
+			t.Errorf("note %d came back as %+v, want %+v\n%s", note.ID, got, note, data)
+		}
+	}
+}
+
+func TestParseForeignMarkdownNote(t *testing.T) {
+	modified := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	created := time.Date(2026, 9, 1, 8, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name string
+		data string
+		want Note
+	}{
+		{"plain text", "Buy milk\n", Note{Content: "Buy milk", CreatedAt: modified}},
+		{"heading", "# Ideas\n\n- one\n- two\n", Note{Title: "Ideas", Content: "- one\n- two", CreatedAt: modified}},
+		{"windows line ends", "---\r\ntitle: Win\r\n---\r\nbody\r\n", Note{Title: "Win", Content: "body", CreatedAt: modified}},
+		{"front matter without id", "---\ncreated_at: 2026-09-01T08:00:00Z\ntags: [x]\n---\n# Heading\ntext",
+			Note{Title: "Heading", Content: "text", Tags: []string{"x"}, CreatedAt: created}},
+		{"only front matter", "---\nid: 4\ntitle: Empty\n---", Note{ID: 4, Title: "Empty", CreatedAt: modified}},
+		{"rule without front matter", "intro\n---\nmore", Note{Content: "intro\n---\nmore", CreatedAt: modified}},
+	} {
+		got, err := parseMarkdownNote([]byte(tc.data), modified)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+	if _, err := parseMarkdownNote([]byte("---\nid: [\n---\nx"), modified); err == nil {
+		t.Error("invalid front matter was accepted")
+	}
+}
+
+func TestMarkdownNoteStore(t *testing.T) {
+	dir := t.TempDir()
+	created := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+	notes := []Note{
+		{ID: 1, Title: "Roadmap", Content: "Q4 #work", Tags: []string{"work"}, CreatedAt: created},
+		{ID: 2, Title: "Groceries", Content: "milk", CreatedAt: created},
+	}
+	files := func() string {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return strings.Join(names, " ")
+	}
+
+	store := &markdownNoteStore{dir: dir}
+	if err := store.write(notes); err != nil {
+		t.Fatal(err)
+	}
+	if got := files(); got != "1-roadmap.md 2-groceries.md" {
+		t.Errorf("files = %s", got)
+	}
+	read, err := (&markdownNoteStore{dir: dir}).read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, notes) {
+		t.Errorf("read back %+v, want %+v", read, notes)
+	}
+
+	// Retitling renames the file and deleting a note removes it; a file
+	// dropped in by hand becomes a note with a new ID.
+	if err := os.WriteFile(filepath.Join(dir, "shopping.md"), []byte("# Shopping\neggs\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store = &markdownNoteStore{dir: dir}
+	if read, err = store.read(); err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 3 || read[2].ID != 3 || read[2].Title != "Shopping" {
+		t.Fatalf("hand-written note read as %+v", read)
+	}
+	read[0].Title = "Plan for Q4"
+	read = append(read[:1], read[2])
+	if err := store.write(read); err != nil {
+		t.Fatal(err)
+	}
+	if got := files(); got != "1-plan-for-q4.md 3-shopping.md" {
+		t.Errorf("files after changes = %s", got)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// noteSearchFields lists the parts of a note "note find --in" can search.
+var noteSearchFields = []string{"title", "content", "tags"}
+
+// noteMatch is a note found by "note find", with the parts of its title and
+// content to highlight.
+type noteMatch struct {
+	note                     Note
+	score                    float64
+	titleSpans, contentSpans [][]int
+}
+
+// noteFieldText returns the text of one field of a note. Tags are written
+// as "#tag" so patterns can look for them that way.
+func noteFieldText(note Note, field string) string {
+	switch field {
+	case "title":
+		return note.Title
+	case "content":
+		return note.Content
+	case "tags":
+		if len(note.Tags) == 0 {
+			return ""
+		}
+		return "#" + strings.Join(note.Tags, " #")
+	}
+	return ""
+}
+
+// searchedFields returns the fields to look in: the one given with --in, or
+// all of them.
+func searchedFields(field string) []string {
+	if field == "" {
+		return noteSearchFields
+	}
+	return []string{field}
+}
+
+// setSpans records the highlights found in a field of a match.
+func (m *noteMatch) setSpans(field string, spans [][]int) {
+	switch field {
+	case "title":
+		m.titleSpans = spans
+	case "content":
+		m.contentSpans = spans
+	}
+}
+
+// regexMatch matches a note against a regular expression, scoring it by the
+// number of matches.
+func regexMatch(re *regexp.Regexp, note Note, field string) (noteMatch, bool) {
+	match := noteMatch{note: note}
+	for _, f := range searchedFields(field) {
+		var spans [][]int
+		for _, span := range re.FindAllStringIndex(noteFieldText(note, f), -1) {
+			if span[1] > span[0] {
+				spans = append(spans, span)
+			}
+		}
+		match.score += float64(len(spans))
+		match.setSpans(f, spans)
+	}
+	return match, match.score > 0
+}
+
+// fuzzyMatcher finds words that are close to the words of a query: the same
+// word with a few typos, a word starting with it, or a word containing its
+// letters in order as an abbreviation (so "mtg" finds "meeting").
+type fuzzyMatcher struct {
+	words []string
+	// scores remembers how well each term seen so far matches each word.
+	scores map[string][]float64
+}
+
+// newFuzzyMatcher returns a matcher for the words of a query.
+func newFuzzyMatcher(query string) *fuzzyMatcher {
+	m := &fuzzyMatcher{scores: map[string][]float64{}}
+	for _, tok := range tokenizeNote(query) {
+		m.words = append(m.words, tok.term)
+	}
+	return m
+}
+
+// maxTypos is how many edits a word of n letters may differ by and still
+// match. Very short words must match exactly, or everything would.
+func maxTypos(n int) int {
+	switch {
+	case n < 3:
+		return 0
+	case n < 6:
+		return 1
+	}
+	return 2
+}
+
+// isAbbreviation reports whether w abbreviates t, as "mtg" does
+// "meeting": at least three letters, starting with the same one, that
+// appear in t in order and make up at least 40% of it. Looser
+// subsequences match nearly any long word.
+func isAbbreviation(w, t []rune) bool {
+	return len(w) >= 3 && w[0] == t[0] && 5*len(w) >= 2*len(t) && isSubsequence(w, t)
+}
+
+// fuzzyScore rates how well term matches a query word, from 0 (not at all)
+// to 1 (exactly).
+func fuzzyScore(word, term string) float64 {
+	if term == word {
+		return 1
+	}
+	w, t := []rune(word), []rune(term)
+	if strings.HasPrefix(term, word) {
+		return 0.6 + 0.3*float64(len(w))/float64(len(t))
+	}
+	if d := levenshtein(w, t); d <= maxTypos(len(w)) {
+		return 0.8 * (1 - float64(d)/float64(len(w)+1))
+	}
+	if isAbbreviation(w, t) {
+		return 0.4 * float64(len(w)) / float64(len(t))
+	}
+	return 0
+}
+
+// levenshtein returns the number of single-letter insertions, deletions and
+// substitutions that turn a into b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// isSubsequence reports whether the letters of a appear in b in order.
+func isSubsequence(a, b []rune) bool {
+	i := 0
+	for _, r := range b {
+		if i < len(a) && a[i] == r {
+			i++
+		}
+	}
+	return i == len(a)
+}
+
+// termScores returns how well a term matches each query word.
+func (m *fuzzyMatcher) termScores(term string) []float64 {
+	scores, ok := m.scores[term]
+	if !ok {
+		scores = make([]float64, len(m.words))
+		for i, word := range m.words {
+			scores[i] = fuzzyScore(word, term)
+		}
+		m.scores[term] = scores
+	}
+	return scores
+}
+
+// match matches a note against the query. Every query word must be matched
+// by some word of the note; the note scores the sum of the best matches.
+func (m *fuzzyMatcher) match(note Note, field string) (noteMatch, bool) {
+	match := noteMatch{note: note}
+	best := make([]float64, len(m.words))
+	for _, f := range searchedFields(field) {
+		var spans [][]int
+		for _, tok := range tokenizeNote(noteFieldText(note, f)) {
+			hit := false
+			for i, score := range m.termScores(tok.term) {
+				best[i] = max(best[i], score)
+				hit = hit || score > 0
+			}
+			if hit {
+				spans = append(spans, []int{tok.start, tok.end})
+			}
+		}
+		match.setSpans(f, spans)
+	}
+	for _, score := range best {
+		if score == 0 {
+			return match, false
+		}
+		match.score += score
+	}
+	return match, true
+}
+
+// tagIndex indexes the tags of notes in memory, for searching with --in tags.
+func tagIndex(notes []Note) *noteIndex {
+	idx := newNoteIndex()
+	for _, note := range notes {
+		idx.add(Note{ID: note.ID, Content: strings.Join(note.Tags, " ")})
+	}
+	return idx
+}
+
+// sortMatches orders matches best first, newest first among equals.
+func sortMatches(matches []noteMatch) {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].note.ID > matches[j].note.ID
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"meeting", "meeting", 0},
+		{"meating", "meeting", 1},
+		{"retrospectve", "retrospective", 1},
+		{"kitten", "sitting", 3},
+		{"café", "cafe", 1},
+	} {
+		if got := levenshtein([]rune(tc.a), []rune(tc.b)); got != tc.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestIsSubsequence(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"", "anything", true},
+		{"mtg", "meeting", true},
+		{"mgt", "meeting", false},
+		{"meeting", "mtg", false},
+		{"cfé", "café", true},
+	} {
+		if got := isSubsequence([]rune(tc.a), []rune(tc.b)); got != tc.want {
+			t.Errorf("isSubsequence(%q, %q) = %t, want %t", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	for _, tc := range []struct {
+		word, term string
+		want       string
+	}{
+		{"meeting", "meeting", "1.00"},
+		// Prefixes score by how much of the word they cover.
+		{"meet", "meeting", "0.77"},
+		{"ab", "about", "0.72"},
+		// Typos, allowed by word length.
+		{"meating", "meeting", "0.70"},
+		{"meetnig", "meeting", "0.60"},
+		{"cat", "cut", "0.60"},
+		{"ab", "ac", "0.00"},
+		// Abbreviations.
+		{"mtg", "meeting", "0.17"},
+		{"rtro", "retrospective", "0.00"},
+		{"ab", "alphabet", "0.00"},
+		{"abt", "alphabet", "0.00"},
+		{"tg", "meeting", "0.00"},
+		{"xyz", "meeting", "0.00"},
+	} {
+		if got := fmt.Sprintf("%.2f", fuzzyScore(tc.word, tc.term)); got != tc.want {
+			t.Errorf("fuzzyScore(%q, %q) = %s, want %s", tc.word, tc.term, got, tc.want)
+		}
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	notes := []Note{
+		{ID: 1, Title: "Weekly meeting", Content: "Retrospective and planning"},
+		{ID: 2, Content: "Alphabet soup, absolutely abundant"},
+		{ID: 3, Title: "Groceries", Content: "milk #errands"},
+	}
+	notes[2].setText(notes[2].Title, notes[2].Content)
+	for _, tc := range []struct {
+		query, field, want string
+	}{
+		{"meating", "", "1"},
+		{"mtg plannig", "", "1"},
+		{"ab", "", "2"},
+		{"retrospectve", "title", ""},
+		{"erands", "tags", "3"},
+		{"xyzzy", "", ""},
+	} {
+		m := newFuzzyMatcher(tc.query)
+		got := ""
+		for _, note := range notes {
+			if _, ok := m.match(note, tc.field); ok {
+				got += fmt.Sprint(note.ID)
+			}
+		}
+		if got != tc.want {
+			t.Errorf("fuzzy %q in %q matched %q, want %q", tc.query, tc.field, got, tc.want)
+		}
+	}
+}
+
+func TestRegexMatch(t *testing.T) {
+	note := Note{ID: 1, Title: "Release v1.2", Content: "Ship v1.2 and v1.3 today"}
+	note.setText(note.Title, note.Content+" #work")
+	for _, tc := range []struct {
+		pattern, field string
+		score          float64
+		titleSpans     string
+	}{
+		{`v[0-9]+\.[0-9]+`, "", 3, "[[8 12]]"},
+		{`v[0-9]+\.[0-9]+`, "content", 2, "[]"},
+		{`#work`, "tags", 1, "[]"},
+		{`^`, "", 0, "[]"},
+		{`nothing`, "", 0, "[]"},
+	} {
+		re := regexp.MustCompile(tc.pattern)
+		match, ok := regexMatch(re, note, tc.field)
+		if ok != (tc.score > 0) || match.score != tc.score {
+			t.Errorf("regexMatch(%q, %q) = %v, %t, want score %v", tc.pattern, tc.field, match.score, ok, tc.score)
+		}
+		if got := fmt.Sprint(match.titleSpans); got != tc.titleSpans {
+			t.Errorf("regexMatch(%q, %q) title spans = %s, want %s", tc.pattern, tc.field, got, tc.titleSpans)
+		}
+	}
+}
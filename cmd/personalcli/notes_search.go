@@ -0,0 +1,329 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// snippetWidth is roughly how many bytes of a note a search result shows.
+const snippetWidth = 160
+
+// searchAtom is one thing to look for: a word, a word prefix ("meet*") or a
+// phrase of several words in a row.
+type searchAtom struct {
+	terms  []string
+	prefix bool
+}
+
+// searchClause matches notes matching any of its atoms (joined with OR), or,
+// when negated, excludes them.
+type searchClause struct {
+	atoms   []searchAtom
+	negated bool
+}
+
+// noteSearch is a parsed search query: notes must match every clause.
+type noteSearch struct {
+	clauses []searchClause
+}
+
+// parseNoteSearch parses a search query. Words are ANDed together; OR
+// between two words accepts either; NOT or a leading "-" excludes a word;
+// "quoted words" must appear as a phrase; and a trailing "*" matches any word
+// starting with the prefix. Operators are only recognized in capitals.
+func parseNoteSearch(text string) (noteSearch, error) {
+	var search noteSearch
+	negate, or := false, false
+	addAtom := func(atom searchAtom) {
+		if or && len(search.clauses) > 0 {
+			last := &search.clauses[len(search.clauses)-1]
+			last.atoms = append(last.atoms, atom)
+		} else {
+			search.clauses = append(search.clauses, searchClause{atoms: []searchAtom{atom}, negated: negate})
+		}
+		negate, or = false, false
+	}
+
+	rest := strings.TrimSpace(text)
+	for rest != "" {
+		var word string
+		quoted := false
+		if strings.HasPrefix(rest, `"`) || strings.HasPrefix(rest, `-"`) {
+			if rest[0] == '-' {
+				negate = true
+				rest = rest[1:]
+			}
+			end := strings.Index(rest[1:], `"`)
+			if end == -1 {
+				return search, fmt.Errorf("unterminated quote in %q", text)
+			}
+			word, rest = rest[1:end+1], rest[end+2:]
+			quoted = true
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end == -1 {
+				end = len(rest)
+			}
+			word, rest = rest[:end], rest[end:]
+		}
+		rest = strings.TrimSpace(rest)
+
+		if !quoted {
+			switch word {
+			case "AND":
+				continue
+			case "OR":
+				if len(search.clauses) == 0 || negate || search.clauses[len(search.clauses)-1].negated {
+					return search, fmt.Errorf("OR needs a word on each side")
+				}
+				or = true
+				continue
+			case "NOT":
+				negate = true
+				continue
+			}
+			if len(word) > 1 && word[0] == '-' {
+				negate = true
+				word = word[1:]
+			}
+		}
+
+		atom := searchAtom{prefix: !quoted && strings.HasSuffix(word, "*")}
+		for _, tok := range tokenizeNote(word) {
+			atom.terms = append(atom.terms, tok.term)
+		}
+		if len(atom.terms) == 0 {
+			continue
+		}
+		if len(atom.terms) > 1 {
+			atom.prefix = false
+		}
+		if or && negate {
+			return search, fmt.Errorf("NOT cannot follow OR")
+		}
+		addAtom(atom)
+	}
+
+	if negate || or {
+		return search, fmt.Errorf("query ends with an operator")
+	}
+	if len(search.clauses) == 0 {
+		return search, fmt.Errorf("nothing to search for")
+	}
+	return search, nil
+}
+
+// matches reports whether a search term matches an indexed term.
+func (a searchAtom) matches(query, term string) bool {
+	if a.prefix {
+		return strings.HasPrefix(term, query)
+	}
+	return query == term
+}
+
+// fieldPositions keeps the positions of a note's tokens that lie in the
+// searched field: the title, the content, or both when field is "".
+func (idx *noteIndex) fieldPositions(id int, positions []int, field string) []int {
+	titleLength := idx.Notes[id].TitleLength
+	switch field {
+	case "title":
+		return positions[:sort.SearchInts(positions, titleLength)]
+	case "content":
+		return positions[sort.SearchInts(positions, titleLength):]
+	}
+	return positions
+}
+
+// score returns the notes matching an atom within a field with their BM25
+// scores.
+func (a searchAtom) score(idx *noteIndex, field string, avgLength float64) map[int]float64 {
+	scores := map[int]float64{}
+	if a.prefix {
+		for term, postings := range idx.Postings {
+			if !strings.HasPrefix(term, a.terms[0]) {
+				continue
+			}
+			for id, positions := range postings {
+				if tf := len(idx.fieldPositions(id, positions, field)); tf > 0 {
+					scores[id] += idx.bm25(id, tf, len(postings), avgLength)
+				}
+			}
+		}
+		return scores
+	}
+
+	for id, positions := range idx.Postings[a.terms[0]] {
+		positions = idx.fieldPositions(id, positions, field)
+		if len(positions) == 0 || len(a.terms) > 1 && !a.phraseIn(idx, id, positions, field) {
+			continue
+		}
+		for _, term := range a.terms {
+			postings := idx.Postings[term]
+			scores[id] += idx.bm25(id, len(idx.fieldPositions(id, postings[id], field)), len(postings), avgLength)
+		}
+	}
+	return scores
+}
+
+// phraseIn reports whether the atom's words appear in a row in a field of a
+// note, given the positions of the first word.
+func (a searchAtom) phraseIn(idx *noteIndex, id int, positions []int, field string) bool {
+	for _, pos := range positions {
+		found := true
+		for i, term := range a.terms[1:] {
+			if !containsInt(idx.fieldPositions(id, idx.Postings[term][id], field), pos+i+1) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// containsInt reports whether a sorted list of positions contains pos.
+func containsInt(positions []int, pos int) bool {
+	i := sort.SearchInts(positions, pos)
+	return i < len(positions) && positions[i] == pos
+}
+
+// searchResult is a note found by a search, with its relevance.
+type searchResult struct {
+	id    int
+	score float64
+}
+
+// run finds the notes matching the search in a field ("title", "content",
+// or "" for both), best match first.
+func (s noteSearch) run(idx *noteIndex, field string) []searchResult {
+	avgLength := idx.averageLength()
+	var scores map[int]float64
+	excluded := map[int]bool{}
+	for _, clause := range s.clauses {
+		matched := map[int]float64{}
+		for _, atom := range clause.atoms {
+			for id, score := range atom.score(idx, field, avgLength) {
+				matched[id] += score
+			}
+		}
+		if clause.negated {
+			for id := range matched {
+				excluded[id] = true
+			}
+			continue
+		}
+		if scores == nil {
+			scores = matched
+			continue
+		}
+		for id := range scores {
+			if _, ok := matched[id]; ok {
+				scores[id] += matched[id]
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	// A query of only exclusions matches every other note.
+	if scores == nil {
+		scores = map[int]float64{}
+		for id := range idx.Notes {
+			scores[id] = 0
+		}
+	}
+
+	var results []searchResult
+	for id, score := range scores {
+		if !excluded[id] {
+			results = append(results, searchResult{id, score})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].id > results[j].id
+	})
+	return results
+}
+
+// highlights reports whether a word should be highlighted in results.
+func (s noteSearch) highlights(term string) bool {
+	for _, clause := range s.clauses {
+		if clause.negated {
+			continue
+		}
+		for _, atom := range clause.atoms {
+			for _, query := range atom.terms {
+				if atom.matches(query, term) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// termSpans returns the byte ranges of the words in text to highlight.
+func termSpans(text string, highlight func(term string) bool) [][]int {
+	var spans [][]int
+	for _, tok := range tokenizeNote(text) {
+		if highlight(tok.term) {
+			spans = append(spans, []int{tok.start, tok.end})
+		}
+	}
+	return spans
+}
+
+// snippet returns a short excerpt of text around the first of spans, sorted
+// byte ranges to highlight, with the highlighted parts wrapped in mark.
+func snippet(text string, spans [][]int, mark func(word string) string) string {
+	start, first := 0, 0
+	if len(spans) > 0 {
+		start, first = max(0, spans[0][0]-snippetWidth/4), spans[0][0]
+	}
+	end := min(len(text), max(start+snippetWidth, first))
+	// Cut at word boundaries, or at least between characters.
+	if start > 0 {
+		if i := strings.IndexFunc(text[start:first], unicode.IsSpace); i != -1 {
+			start += i
+		}
+		for start < first && !utf8.RuneStart(text[start]) {
+			start++
+		}
+	}
+	if end < len(text) {
+		if i := strings.LastIndexFunc(text[start:end], unicode.IsSpace); i > first-start {
+			end = start + i
+		}
+		for end > start && !utf8.RuneStart(text[end]) {
+			end--
+		}
+	}
+
+	var b strings.Builder
+	pos := start
+	for _, span := range spans {
+		if span[0] < pos || span[1] > end {
+			continue
+		}
+		b.WriteString(text[pos:span[0]])
+		b.WriteString(mark(text[span[0]:span[1]]))
+		pos = span[1]
+	}
+	b.WriteString(text[pos:end])
+
+	excerpt := strings.Join(strings.Fields(b.String()), " ")
+	if start > 0 {
+		excerpt = "…" + excerpt
+	}
+	if end < len(text) {
+		excerpt += "…"
+	}
+	return excerpt
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// describeSearch renders a parsed search compactly, e.g. "meet* | -budget".
+func describeSearch(s noteSearch) string {
+	var clauses []string
+	for _, clause := range s.clauses {
+		var atoms []string
+		for _, atom := range clause.atoms {
+			text := strings.Join(atom.terms, " ")
+			if len(atom.terms) > 1 {
+				text = `"` + text + `"`
+			}
+			if atom.prefix {
+				text += "*"
+			}
+			atoms = append(atoms, text)
+		}
+		text := strings.Join(atoms, " OR ")
+		if clause.negated {
+			text = "-" + text
+		}
+		clauses = append(clauses, text)
+	}
+	return strings.Join(clauses, " | ")
+}
+
+func TestParseNoteSearch(t *testing.T) {
+	for _, tc := range []struct {
+		query, want string
+	}{
+		{"meeting", "meeting"},
+		{"Meeting NOTES", "meeting | notes"},
+		{"meeting AND notes", "meeting | notes"},
+		{"meeting OR call", "meeting OR call"},
+		{"a OR b OR c d", "a OR b OR c | d"},
+		{"meeting NOT budget", "meeting | -budget"},
+		{"meeting -budget", "meeting | -budget"},
+		{`"project plan" review`, `"project plan" | review`},
+		{`-"project plan"`, `-"project plan"`},
+		{`"single"`, "single"},
+		{"meet*", "meet*"},
+		{"Café*", "café*"},
+		{"-", ""},
+		// Lowercase operators are plain words.
+		{"this or that", "this | or | that"},
+		{"well-known", `"well known"`},
+		{"NOT budget", "-budget"},
+	} {
+		search, err := parseNoteSearch(tc.query)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("parseNoteSearch(%q) = %s, want an error", tc.query, describeSearch(search))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseNoteSearch(%q): %v", tc.query, err)
+			continue
+		}
+		if got := describeSearch(search); got != tc.want {
+			t.Errorf("parseNoteSearch(%q) = %s, want %s", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestParseNoteSearchErrors(t *testing.T) {
+	for _, query := range []string{"", "   ", `"unterminated`, "OR meeting", "meeting OR", "meeting NOT",
+		"NOT OR x", "a OR NOT b", "a OR -b", "!!!"} {
+		if search, err := parseNoteSearch(query); err == nil {
+			t.Errorf("parseNoteSearch(%q) = %s, want an error", query, describeSearch(search))
+		}
+	}
+}
+
+func TestNoteSearchRun(t *testing.T) {
+	idx := newNoteIndex()
+	for _, note := range []Note{
+		{ID: 1, Title: "Weekly meeting", Content: "Budget review and project plan"},
+		{ID: 2, Content: "Meeting with the design team about the plan for the project"},
+		{ID: 3, Title: "Groceries", Content: "milk, eggs, bread"},
+		{ID: 4, Content: "meetup downtown: meeting people, meeting friends"},
+	} {
+		idx.add(note)
+	}
+
+	for _, tc := range []struct {
+		query, field, want string
+	}{
+		{"meeting", "", "4,1,2"},
+		{"meeting", "title", "1"},
+		{"meet*", "", "4,1,2"},
+		{`"project plan"`, "", "1"},
+		{"meeting -budget", "", "4,2"},
+		{"milk OR budget", "", "3,1"},
+		{"NOT meeting", "", "3"},
+		{"plan project", "content", "1,2"},
+		{"nothing", "", ""},
+	} {
+		search, err := parseNoteSearch(tc.query)
+		if err != nil {
+			t.Fatalf("parseNoteSearch(%q): %v", tc.query, err)
+		}
+		var ids []string
+		for _, result := range search.run(idx, tc.field) {
+			ids = append(ids, fmt.Sprint(result.id))
+		}
+		if got := strings.Join(ids, ","); got != tc.want {
+			t.Errorf("search %q in %q = %s, want %s", tc.query, tc.field, got, tc.want)
+		}
+	}
+}
+
+func TestUpdateNoteIndexRewritesOnlyChangedNotes(t *testing.T) {
+	dir, store := notesConfigDir, activeNoteStore
+	notesConfigDir = t.TempDir()
+	activeNoteStore = &markdownNoteStore{dir: filepath.Join(notesConfigDir, "notes")}
+	t.Cleanup(func() { notesConfigDir, activeNoteStore = dir, store })
+
+	notes := []Note{{ID: 1, Content: "alpha"}, {ID: 2, Content: "beta"}, {ID: 3, Content: "gamma"}}
+	if _, err := updateNoteIndex(notes, false); err != nil {
+		t.Fatal(err)
+	}
+	segment := filepath.Join(noteIndexDirPath(), segmentFileName(notes[0]))
+	// Mark an unchanged note's segment so a rewrite would show.
+	if err := os.WriteFile(segment, []byte(`{"length":1,"title_length":0,"postings":{"marked":[0]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	notes[1].Content = "beta changed"
+	notes = notes[:2]
+	idx, err := syncNoteIndex(notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.Postings["marked"][1]; !ok {
+		t.Error("the unchanged note was re-indexed")
+	}
+	if _, ok := idx.Postings["changed"][2]; !ok {
+		t.Error("the changed note was not re-indexed")
+	}
+	entries, err := os.ReadDir(noteIndexDirPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := []string{segmentFileName(notes[0]), segmentFileName(notes[1]), "meta.json"}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Errorf("index files = %v, want %v", names, want)
+	}
+
+	// Moving to another store starts the index over.
+	activeNoteStore = &markdownNoteStore{dir: filepath.Join(notesConfigDir, "elsewhere")}
+	if idx, _ = syncNoteIndex(notes); idx.Postings["marked"] != nil {
+		t.Error("the index of another store was reused")
+	}
+}
+
+func TestNoteIndexMergesSegmentsIntoBase(t *testing.T) {
+	dir, store := notesConfigDir, activeNoteStore
+	notesConfigDir = t.TempDir()
+	activeNoteStore = &markdownNoteStore{dir: filepath.Join(notesConfigDir, "notes")}
+	t.Cleanup(func() { notesConfigDir, activeNoteStore = dir, store })
+
+	indexFiles := func() []string {
+		entries, err := os.ReadDir(noteIndexDirPath())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	var notes []Note
+	for id := 1; id <= noteIndexMaxPending+1; id++ {
+		notes = append(notes, Note{ID: id, Content: fmt.Sprintf("note number%d", id)})
+	}
+	if _, err := updateNoteIndex(notes, false); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(indexFiles()); n != len(notes)+1 {
+		t.Fatalf("%d index files before the merge, want one per note and meta.json", n)
+	}
+
+	// Loading with too many separate segments merges them into the base.
+	idx, err := syncNoteIndex(notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(indexFiles(), " "); got != "base.json meta.json" {
+		t.Fatalf("index files after the merge = %s", got)
+	}
+	if len(idx.Notes) != len(notes) || len(idx.Postings["note"]) != len(notes) {
+		t.Errorf("merged index has %d notes, want %d", len(idx.Notes), len(notes))
+	}
+
+	// A changed note gets a separate segment again; its old version in the
+	// base no longer counts, and neither does a deleted note.
+	notes[0].Content = "rewritten"
+	notes = notes[:len(notes)-1]
+	if _, err := updateNoteIndex(notes, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(indexFiles(), " "); got != "1-"+noteHash(notes[0])+".json base.json meta.json" {
+		t.Errorf("index files after a change = %s", got)
+	}
+	idx, err = syncNoteIndex(notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.Postings["rewritten"][1]; !ok {
+		t.Error("the changed note is missing its new text")
+	}
+	if idx.Postings["number1"] != nil || len(idx.Notes) != len(notes) {
+		t.Error("stale base entries were loaded")
+	}
+}
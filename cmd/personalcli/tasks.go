@@ -1,22 +1,105 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 )
 
 // Task represents a single todo item.
 type Task struct {
-	ID          int    `json:"id"`
-	Description string `json:"description"`
-	Completed   bool   `json:"completed"`
+	ID          int               `json:"id"`
+	Description string            `json:"description"`
+	Status      string            `json:"status"`
+	Due         *time.Time        `json:"due,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Project     string            `json:"project,omitempty"`
+	Recur       string            `json:"recur,omitempty"`
+	Parent      int               `json:"parent,omitempty"`
+	BlockedBy   []int             `json:"blocked_by,omitempty"`
+	Intervals   []timeInterval    `json:"intervals,omitempty"`
+	Pomodoros   []pomodoro        `json:"pomodoros,omitempty"`
+	Contexts    []string          `json:"contexts,omitempty"`
+	Extras      map[string]string `json:"extras,omitempty"`
+	TodoTxt     string            `json:"todotxt,omitempty"`
+	CreatedAt   *time.Time        `json:"created_at,omitempty"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+	ModifiedAt  *time.Time        `json:"modified_at,omitempty"`
+	UUID        string            `json:"uuid,omitempty"`
+	Annotations []taskAnnotation  `json:"annotations,omitempty"`
 }
 
-// tasksFilePath is the path to the JSON file where tasks are stored.
+// Workflow states a task moves through. Tasks start in statusTodo.
+const (
+	statusBacklog = "backlog"
+	statusTodo    = "todo"
+	statusDoing   = "doing"
+	statusBlocked = "blocked"
+	statusDone    = "done"
+)
+
+// taskStatuses lists the workflow states in board order.
+var taskStatuses = []string{statusBacklog, statusTodo, statusDoing, statusBlocked, statusDone}
+
+// parseStatus normalizes a workflow state name.
+func parseStatus(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "wip", "progress", "in-progress":
+		return statusDoing, nil
+	case "waiting":
+		return statusBlocked, nil
+	case "completed":
+		return statusDone, nil
+	}
+	if slices.Contains(taskStatuses, value) {
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid status %q (use %s)", value, strings.Join(taskStatuses, ", "))
+}
+
+// UnmarshalJSON reads a task, upgrading files written before tasks had a
+// status, which only recorded a "completed" flag.
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plainTask Task
+	legacy := struct {
+		*plainTask
+		Completed bool `json:"completed"`
+	}{plainTask: (*plainTask)(t)}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	if t.Status == "" {
+		t.Status = statusTodo
+		if legacy.Completed {
+			t.Status = statusDone
+		}
+	}
+	return nil
+}
+
+// isDone reports whether the task is finished.
+func (t Task) isDone() bool {
+	return t.Status == statusDone
+}
+
+// dueDateLayout is the format used for entering and displaying due dates.
+const dueDateLayout = "2006-01-02"
+
+// tasksFilePath is the path to the JSON file where the current list's tasks
+// are stored. It starts out as the default list and is switched by useList.
 var tasksFilePath string
 
+// tasksDir is the directory that holds the task lists.
+var tasksDir string
+
 func init() {
 	// Get home directory
 	home, err := os.UserHomeDir()
@@ -24,13 +107,13 @@ func init() {
 		fmt.Println("Error finding home directory:", err)
 		os.Exit(1)
 	}
-	
+
 	// Define the path for the tasks file
-	configDir := filepath.Join(home, ".config", "personalcli")
-	tasksFilePath = filepath.Join(configDir, "tasks.json")
+	tasksDir = filepath.Join(home, ".config", "personalcli")
+	tasksFilePath = filepath.Join(tasksDir, "tasks.json")
 
 	// Create the directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(tasksDir, 0755); err != nil {
 		fmt.Println("Error creating config directory:", err)
 		os.Exit(1)
 	}
@@ -63,3 +146,156 @@ func writeTasks(tasks []Task) error {
 	}
 	return os.WriteFile(tasksFilePath, data, 0644)
 }
+
+// nextTaskID returns an ID one higher than any existing task's, including
+// the tasks archived from the current list.
+func nextTaskID(tasks []Task) int {
+	// An unreadable archive is reported by the commands that use it.
+	maxID, _ := archivedMaxID()
+	for _, task := range tasks {
+		if task.ID > maxID {
+			maxID = task.ID
+		}
+	}
+	return maxID + 1
+}
+
+// complete marks the task as done at now and stops its timer.
+func (t *Task) complete(now time.Time) {
+	t.Status = statusDone
+	t.CompletedAt = &now
+	stopTimer(t, now)
+}
+
+// reopen moves a finished task back to the todo column.
+func (t *Task) reopen() {
+	t.Status = statusTodo
+	t.CompletedAt = nil
+}
+
+// stampModified sets ModifiedAt to now on every task in after that is new or
+// differs from its version in before.
+func stampModified(before, after []Task, now time.Time) {
+	old := map[int][]byte{}
+	for _, task := range before {
+		task.ModifiedAt = nil
+		old[task.ID], _ = json.Marshal(task)
+	}
+	for i := range after {
+		task := after[i]
+		task.ModifiedAt = nil
+		data, _ := json.Marshal(task)
+		if prev, ok := old[task.ID]; !ok || !bytes.Equal(prev, data) {
+			after[i].ModifiedAt = &now
+		}
+	}
+}
+
+// lastModified returns when the task was last changed, falling back to its
+// creation time for tasks saved before changes were timestamped.
+func (t Task) lastModified() time.Time {
+	switch {
+	case t.ModifiedAt != nil:
+		return *t.ModifiedAt
+	case t.CreatedAt != nil:
+		return *t.CreatedAt
+	}
+	return time.Time{}
+}
+
+// findTask returns the index of the task with the given ID, or -1.
+func findTask(tasks []Task, id int) int {
+	for i := range tasks {
+		if tasks[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// parsePriority normalizes a priority flag value to "H", "M" or "L".
+// An empty value means the task has no priority.
+func parsePriority(value string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "":
+		return "", nil
+	case "H", "HIGH":
+		return "H", nil
+	case "M", "MEDIUM":
+		return "M", nil
+	case "L", "LOW":
+		return "L", nil
+	}
+	return "", fmt.Errorf("invalid priority %q (use H, M or L)", value)
+}
+
+// parseDueDate resolves a due date expression such as "tomorrow 5pm" or
+// "2026-11-02" against now. An empty value means the task has no due date.
+func parseDueDate(value string, now time.Time) (*time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	t, err := parseDate(value, now)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// priorityRank orders priorities from most to least urgent. Tasks without
+// a priority sort after all prioritized ones.
+func priorityRank(priority string) int {
+	switch priority {
+	case "H":
+		return 0
+	case "M":
+		return 1
+	case "L":
+		return 2
+	}
+	return 3
+}
+
+// isOverdue reports whether an open task's due date is before today.
+func isOverdue(task Task, now time.Time) bool {
+	if task.isDone() || task.Due == nil {
+		return false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return task.Due.Before(today)
+}
+
+// sortTasks orders tasks in place by "id", "due" or "priority". Ties are
+// broken by ID so the listing is stable. "manual" keeps the order of the
+// tasks file, which "todo ui" lets you rearrange.
+func sortTasks(tasks []Task, by string) error {
+	var less func(a, b Task) bool
+	switch by {
+	case "manual":
+		return nil
+	case "", "id":
+		less = func(a, b Task) bool { return false }
+	case "due":
+		less = func(a, b Task) bool {
+			if a.Due == nil || b.Due == nil {
+				return a.Due != nil && b.Due == nil
+			}
+			return a.Due.Before(*b.Due)
+		}
+	case "priority":
+		less = func(a, b Task) bool { return priorityRank(a.Priority) < priorityRank(b.Priority) }
+	default:
+		return fmt.Errorf("invalid sort key %q (use id, due, priority or manual)", by)
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if less(tasks[i], tasks[j]) {
+			return true
+		}
+		if less(tasks[j], tasks[i]) {
+			return false
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return nil
+}
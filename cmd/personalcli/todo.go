@@ -3,8 +3,10 @@ package main
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -19,7 +21,7 @@ var todoCmd = &cobra.Command{
 }
 
 var addCmd = &cobra.Command{
-	Use:   "add [task description]",
+	Use:   "add [task description] [+tag...] [project:name]",
 	Short: "Add a new task to your todo list",
 	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -29,30 +31,135 @@ var addCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		newID := 1
-		if len(tasks) > 0 {
-			newID = tasks[len(tasks)-1].ID + 1
+		now := currentTime()
+		dueFlag, _ := cmd.Flags().GetString("due")
+		due, err := parseDueDate(dueFlag, now)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
 		}
 
+		priorityFlag, _ := cmd.Flags().GetString("priority")
+		priority, err := parsePriority(priorityFlag)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		recur, _ := cmd.Flags().GetString("recur")
+		if recur != "" {
+			if _, err := parseRecurrence(recur); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		statusFlag, _ := cmd.Flags().GetString("status")
+		status, err := parseStatus(statusFlag)
+		if err == nil && status == statusDone {
+			err = fmt.Errorf("new tasks cannot start as done")
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		parent, _ := cmd.Flags().GetInt("parent")
+		if err := validateParent(tasks, 0, parent); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		blockedBy, _ := cmd.Flags().GetIntSlice("blocked-by")
+		if err := validateBlockers(tasks, 0, blockedBy); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		description, tags, project := parseTaskTokens(args)
+		if description == "" {
+			fmt.Println("Error: task description cannot be empty.")
+			os.Exit(1)
+		}
+
+		created := time.Now()
 		newTask := Task{
-			ID:          newID,
-			Description: strings.Join(args, " "),
-			Completed:   false,
+			ID:          nextTaskID(tasks),
+			Description: description,
+			Status:      status,
+			CreatedAt:   &created,
+			Due:         due,
+			Priority:    priority,
+			Tags:        tags,
+			Project:     project,
+			Recur:       strings.ToLower(recur),
+			Parent:      parent,
+			BlockedBy:   blockedBy,
 		}
 
 		tasks = append(tasks, newTask)
-		if err := writeTasks(tasks); err != nil {
+		if err := saveTasks(fmt.Sprintf("add %d", newTask.ID), tasks); err != nil {
 			fmt.Println("Error writing tasks:", err)
 			os.Exit(1)
 		}
 		fmt.Printf("Added task: \"%s\"\n", newTask.Description)
+		if due != nil {
+			fmt.Printf("Due %s (%s).\n", formatDue(*due), due.Format("Monday"))
+		}
 	},
 }
 
 var listCmd = &cobra.Command{
-	Use:   "list",
+	Use:   "list [query]",
 	Short: "List all of your tasks",
+	Long: `List your tasks, optionally filtered by a query such as
+
+  personalcli todo list "status:open and (due<friday or priority:H) and +work"
+
+Terms: +tag, @context, project:name, priority:H|M|L|none, due:<date>|none|any,
+id:N, status:open or a workflow state (backlog, todo, doing, blocked, done),
+and plain words or "quoted phrases" that match the description. Compare with
+: = != < <= > >= and combine with and, or, not and parentheses; terms next to
+each other are joined with and. Dates can be today, tomorrow, yesterday, a
+weekday, eow, eom, eoy or YYYY-MM-DD.
+
+With --all, every todo list is shown, one after the other.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		queryText := strings.Join(args, " ")
+		query, err := parseQuery(queryText, currentTime())
+		if err != nil {
+			fmt.Println("Error in query:", err)
+			if qerr, ok := err.(*queryError); ok {
+				fmt.Println(qerr.explain(queryText))
+			}
+			os.Exit(1)
+		}
+
+		sortBy, _ := cmd.Flags().GetString("sort")
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			names, err := todoLists()
+			if err != nil {
+				fmt.Println("Error finding lists:", err)
+				os.Exit(1)
+			}
+			for i, name := range names {
+				if i > 0 {
+					fmt.Println()
+				}
+				useList(name)
+				tasks, err := readTasks()
+				if err != nil {
+					fmt.Printf("Error reading list %s: %v\n", name, err)
+					os.Exit(1)
+				}
+				if err := printTasks(name+":", tasks, query, sortBy); err != nil {
+					fmt.Println("Error:", err)
+					os.Exit(1)
+				}
+			}
+			return
+		}
+
 		tasks, err := readTasks()
 		if err != nil {
 			fmt.Println("Error reading tasks:", err)
@@ -64,25 +171,125 @@ var listCmd = &cobra.Command{
 			return
 		}
 
-		fmt.Println("Your tasks:")
-		for _, task := range tasks {
-			status := " "
-			if task.Completed {
-				status = "✔"
-			}
-			fmt.Printf("[%s] %d: %s\n", status, task.ID, task.Description)
+		title := "Your tasks:"
+		if currentList != defaultListName {
+			title = fmt.Sprintf("Your tasks (%s):", currentList)
+		}
+		if err := printTasks(title, tasks, query, sortBy); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
 		}
 	},
 }
 
+// printTasks prints the tasks that match query as a tree under title.
+func printTasks(title string, tasks []Task, query taskQuery, sortBy string) error {
+	if err := sortTasks(tasks, sortBy); err != nil {
+		return err
+	}
+
+	var visible []Task
+	for _, task := range tasks {
+		if query.matches(task) {
+			visible = append(visible, task)
+		}
+	}
+
+	fmt.Println(title)
+	if len(visible) == 0 {
+		fmt.Println("No tasks match the filter.")
+	}
+	for _, line := range renderTaskTree(visible, tasks, currentTime()) {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// statusMarks is the checkbox shown for each workflow state in listings.
+var statusMarks = map[string]string{
+	statusBacklog: "~",
+	statusTodo:    " ",
+	statusDoing:   ">",
+	statusBlocked: "!",
+	statusDone:    "✔",
+}
+
+// formatTask renders a task as a single line for listing. Overdue tasks are
+// highlighted when writing to a terminal.
+func formatTask(task Task, now time.Time) string {
+	status, ok := statusMarks[task.Status]
+	if !ok {
+		status = " "
+	}
+	line := fmt.Sprintf("[%s] %d: %s", status, task.ID, task.Description)
+	if task.Project != "" {
+		line += " " + projectPrefix + task.Project
+	}
+	for _, tag := range task.Tags {
+		line += " +" + tag
+	}
+	for _, context := range task.Contexts {
+		line += " @" + context
+	}
+
+	var details []string
+	if task.Priority != "" {
+		details = append(details, "priority "+task.Priority)
+	}
+	if task.Due != nil {
+		details = append(details, "due "+formatDue(*task.Due))
+	}
+	if task.Recur != "" {
+		details = append(details, "repeats "+task.Recur)
+	}
+	if len(task.BlockedBy) > 0 {
+		ids := make([]string, len(task.BlockedBy))
+		for i, id := range task.BlockedBy {
+			ids[i] = strconv.Itoa(id)
+		}
+		details = append(details, "blocked by "+strings.Join(ids, ", "))
+	}
+	if n := completedPomodoros(task); n > 0 {
+		details = append(details, fmt.Sprintf("%d pomodoro(s)", n))
+	}
+	if len(details) > 0 {
+		line += " (" + strings.Join(details, ", ") + ")"
+	}
+
+	if isOverdue(task, now) {
+		line += " OVERDUE"
+		if isTerminal(os.Stdout) {
+			line = "\033[31m" + line + "\033[0m"
+		}
+	}
+	return line
+}
+
+// isTerminal reports whether f is attached to a terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 var doneCmd = &cobra.Command{
-	Use:   "done [task_id]",
-	Short: "Mark a task as completed",
-	Args:  cobra.ExactArgs(1),
+	Use:   "done [selector...]",
+	Short: "Mark tasks as completed",
+	Long: `Mark tasks as completed. Select tasks by ID, range or query, e.g.
+
+  personalcli todo done 3 5 7-9
+  personalcli todo done +errands
+
+Every selected task is handled in one go; tasks that cannot be completed are
+reported and the rest are still marked done.`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		taskID, err := strconv.Atoi(args[0])
+		now := currentTime()
+		sel, err := parseSelector(args, now)
 		if err != nil {
-			fmt.Println("Invalid task ID. Please provide a number.")
+			reportSelectorError(err, args)
 			os.Exit(1)
 		}
 
@@ -92,40 +299,340 @@ var doneCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		taskFound := false
-		for i := range tasks {
-			if tasks[i].ID == taskID {
-				tasks[i].Completed = true
-				taskFound = true
-				break
+		ids, missing := sel.selectTasks(tasks)
+		failed := reportMissing(missing)
+		deepestFirst(tasks, ids)
+
+		cascade, _ := cmd.Flags().GetBool("cascade")
+		var completed []int
+		var scheduled []*Task
+		for _, taskID := range ids {
+			taskIndex := findTask(tasks, taskID)
+			if tasks[taskIndex].isDone() {
+				fmt.Printf("Task %d is already completed.\n", taskID)
+				continue
+			}
+			// A parent can only be closed once its subtasks are, unless asked to cascade.
+			if open := openChildren(tasks, taskID); len(open) > 0 && !cascade {
+				fmt.Printf("Task %d has %d open subtask(s). Finish them first or use --cascade.\n", taskID, len(open))
+				failed = true
+				continue
+			}
+			tasks[taskIndex].complete(time.Now())
+			if cascade {
+				for _, id := range descendants(tasks, taskID) {
+					if child := &tasks[findTask(tasks, id)]; !child.isDone() {
+						child.complete(time.Now())
+					}
+				}
+			}
+
+			grown, next, err := scheduleNext(tasks, taskIndex, now)
+			if err != nil {
+				fmt.Printf("Error scheduling next occurrence of task %d: %v\n", taskID, err)
+				failed = true
+			} else {
+				tasks = grown
+			}
+			completed = append(completed, taskID)
+			scheduled = append(scheduled, next)
+		}
+
+		if len(completed) > 0 {
+			if err := saveTasks("done "+strings.Join(args, " "), tasks); err != nil {
+				fmt.Println("Error writing tasks:", err)
+				os.Exit(1)
+			}
+		}
+
+		for i, taskID := range completed {
+			fmt.Printf("Marked task %d as completed.\n", taskID)
+			if next := scheduled[i]; next != nil {
+				fmt.Printf("Next occurrence is task %d, due %s.\n", next.ID, formatDue(*next.Due))
 			}
 		}
+		if len(ids) == 0 && !failed {
+			fmt.Println("No tasks match the selection.")
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
 
-		if !taskFound {
-			fmt.Println("Task ID not found.")
+var undoneCmd = &cobra.Command{
+	Use:   "undone [selector...]",
+	Short: "Mark completed tasks as open again",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sel, err := parseSelector(args, currentTime())
+		if err != nil {
+			reportSelectorError(err, args)
 			os.Exit(1)
 		}
 
-		if err := writeTasks(tasks); err != nil {
-			fmt.Println("Error writing tasks:", err)
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+
+		ids, missing := sel.selectTasks(tasks)
+		failed := reportMissing(missing)
+		for _, taskID := range ids {
+			tasks[findTask(tasks, taskID)].reopen()
+		}
+
+		if len(ids) > 0 {
+			if err := saveTasks("undone "+strings.Join(args, " "), tasks); err != nil {
+				fmt.Println("Error writing tasks:", err)
+				os.Exit(1)
+			}
+		}
+
+		for _, taskID := range ids {
+			fmt.Printf("Marked task %d as open.\n", taskID)
+		}
+		if len(ids) == 0 && !failed {
+			fmt.Println("No tasks match the selection.")
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// reportMissing prints a line for each selected ID or range that matches no
+// task and reports whether there were any.
+func reportMissing(missing []idRange) bool {
+	for _, r := range missing {
+		if r.lo == r.hi {
+			fmt.Printf("Task ID %d not found.\n", r.lo)
+		} else {
+			fmt.Printf("No tasks found in range %s.\n", r)
+		}
+	}
+	return len(missing) > 0
+}
+
+var editCmd = &cobra.Command{
+	Use:   "edit [task_id...] [new description] [+tag...] [project:name]",
+	Short: "Change tasks' description, tags, due date, priority or recurrence",
+	Long: `Change existing tasks. The first argument selects the tasks to change: an ID,
+a range or a comma list (e.g. "3,5,7-9"). To select with several arguments,
+end them with "--" (e.g. "todo edit 3 5 7-9 -- +urgent"). --filter selects
+by query instead, or narrows the IDs.
+
+Any description words replace the current description (only when a single
+task is selected), +tag tokens add tags and project:name sets the project.
+Flags that are given replace the matching field; pass an empty value
+(e.g. --due "") to clear it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Only the first argument selects, so "todo edit 1 2 apples" renames
+		// task 1, unless the selection is ended with "--".
+		split := cmd.ArgsLenAtDash()
+		if split == -1 {
+			split = 0
+			if len(args) > 0 {
+				if _, ok, _ := parseIDList(args[0]); ok {
+					split = 1
+				}
+			}
+		}
+		selArgs := args[:split]
+		if filter, _ := cmd.Flags().GetString("filter"); filter != "" {
+			selArgs = append(selArgs[:split:split], filter)
+		}
+		now := currentTime()
+		sel, err := parseSelector(selArgs, now)
+		if err != nil {
+			reportSelectorError(err, selArgs)
+			os.Exit(1)
+		}
+
+		// Validate the new field values once, before touching any task.
+		var due *time.Time
+		if cmd.Flags().Changed("due") {
+			dueFlag, _ := cmd.Flags().GetString("due")
+			if due, err = parseDueDate(dueFlag, now); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+		var priority string
+		if cmd.Flags().Changed("priority") {
+			priorityFlag, _ := cmd.Flags().GetString("priority")
+			if priority, err = parsePriority(priorityFlag); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+		recur, _ := cmd.Flags().GetString("recur")
+		if recur != "" {
+			if _, err := parseRecurrence(recur); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+
+		ids, missing := sel.selectTasks(tasks)
+		failed := reportMissing(missing)
+		description, tags, project := parseTaskTokens(args[split:])
+		if description != "" && len(ids) > 1 {
+			fmt.Printf("Refusing to give %d tasks the same description; select a single task.\n", len(ids))
+			os.Exit(1)
+		}
+
+		var updated []int
+		for _, taskID := range ids {
+			task := &tasks[findTask(tasks, taskID)]
+
+			if cmd.Flags().Changed("parent") {
+				parent, _ := cmd.Flags().GetInt("parent")
+				if err := validateParent(tasks, taskID, parent); err != nil {
+					fmt.Printf("Task %d: %v\n", taskID, err)
+					failed = true
+					continue
+				}
+				task.Parent = parent
+			}
+
+			blockedBy := slices.Clone(task.BlockedBy)
+			moreBlockers, _ := cmd.Flags().GetIntSlice("blocked-by")
+			for _, id := range moreBlockers {
+				if !slices.Contains(blockedBy, id) {
+					blockedBy = append(blockedBy, id)
+				}
+			}
+			unblock, _ := cmd.Flags().GetIntSlice("unblock")
+			blockedBy = slices.DeleteFunc(blockedBy, func(id int) bool { return slices.Contains(unblock, id) })
+			if err := validateBlockers(tasks, taskID, blockedBy); err != nil {
+				fmt.Printf("Task %d: %v\n", taskID, err)
+				failed = true
+				continue
+			}
+			task.BlockedBy = blockedBy
+
+			if description != "" {
+				task.Description = description
+			}
+			for _, tag := range tags {
+				task.Tags = addTag(task.Tags, tag)
+			}
+			if project != "" {
+				task.Project = project
+			}
+			untag, _ := cmd.Flags().GetStringSlice("untag")
+			for _, tag := range untag {
+				task.Tags = removeTag(task.Tags, strings.TrimPrefix(tag, "+"))
+			}
+			if cmd.Flags().Changed("due") {
+				task.Due = due
+			}
+			if cmd.Flags().Changed("priority") {
+				task.Priority = priority
+			}
+			if cmd.Flags().Changed("recur") {
+				task.Recur = strings.ToLower(recur)
+			}
+			updated = append(updated, taskID)
+		}
+
+		if len(updated) > 0 {
+			if err := saveTasks("edit "+strings.Join(args, " "), tasks); err != nil {
+				fmt.Println("Error writing tasks:", err)
+				os.Exit(1)
+			}
+			fmt.Println("Updated tasks:")
+			for _, taskID := range updated {
+				fmt.Println(formatTask(tasks[findTask(tasks, taskID)], now))
+			}
+		}
+		if len(ids) == 0 && !failed {
+			fmt.Println("No tasks match the selection.")
+		}
+		if failed {
 			os.Exit(1)
 		}
+	},
+}
 
-		fmt.Printf("Marked task %d as completed.\n", taskID)
+var rmCmd = &cobra.Command{
+	Use:   "rm [selector...]",
+	Short: "Delete tasks by ID, range or query",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sel, err := parseSelector(args, currentTime())
+		if err != nil {
+			reportSelectorError(err, args)
+			os.Exit(1)
+		}
+
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+
+		ids, missing := sel.selectTasks(tasks)
+		failed := reportMissing(missing)
+		remove := map[int]bool{}
+		for _, taskID := range ids {
+			remove[taskID] = true
+		}
+
+		if len(remove) > 0 {
+			if err := saveTasks("rm "+strings.Join(args, " "), removeTasks(tasks, remove)); err != nil {
+				fmt.Println("Error writing tasks:", err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Printf("Removed %d task(s).\n", len(remove))
+		if failed {
+			os.Exit(1)
+		}
 	},
 }
 
+// removeTasks drops the tasks whose IDs are in remove. Subtasks of a removed
+// task move up to its parent and links to removed blockers are dropped.
+func removeTasks(tasks []Task, remove map[int]bool) []Task {
+	parents := map[int]int{}
+	for _, task := range tasks {
+		parents[task.ID] = task.Parent
+	}
+	kept := tasks[:0]
+	for _, task := range tasks {
+		if remove[task.ID] {
+			continue
+		}
+		for remove[task.Parent] {
+			task.Parent = parents[task.Parent]
+		}
+		task.BlockedBy = slices.DeleteFunc(task.BlockedBy, func(id int) bool { return remove[id] })
+		kept = append(kept, task)
+	}
+	return kept
+}
+
 func init() {
 	// Add a command to clear all tasks
 	var clearCmd = &cobra.Command{
 		Use:   "clear",
-		Short: "Clear all tasks from the list",
+		Short: "Clear all tasks from the list (undo with 'todo undo')",
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := writeTasks([]Task{}); err != nil {
+			if err := saveTasks("clear", []Task{}); err != nil {
 				fmt.Println("Error clearing tasks:", err)
 				os.Exit(1)
 			}
-			fmt.Println("All tasks cleared.")
+			fmt.Println("All tasks cleared. Run 'personalcli todo undo' to restore them.")
 		},
 	}
 
@@ -134,4 +641,25 @@ func init() {
 	todoCmd.AddCommand(listCmd)
 	todoCmd.AddCommand(doneCmd)
 	todoCmd.AddCommand(clearCmd)
+	todoCmd.AddCommand(undoneCmd)
+	todoCmd.AddCommand(editCmd)
+	todoCmd.AddCommand(rmCmd)
+
+	addCmd.Flags().String("due", "", "Due date, e.g. \"tomorrow 5pm\", \"next fri\", \"in 3 days\", eom or YYYY-MM-DD")
+	addCmd.Flags().StringP("priority", "p", "", "Priority: H, M or L")
+	addCmd.Flags().String("status", statusTodo, "Workflow state: backlog, todo, doing or blocked")
+	addCmd.Flags().Int("parent", 0, "ID of the task this is a subtask of")
+	addCmd.Flags().IntSlice("blocked-by", nil, "IDs of tasks that must be done first")
+	addCmd.Flags().String("recur", "", "Repeat rule: daily, weekdays, weekly[:mon,thu], monthly:N or every:Nd")
+	editCmd.Flags().String("due", "", "New due date (same forms as todo add --due), empty to clear")
+	editCmd.Flags().StringP("priority", "p", "", "New priority: H, M or L, empty to clear")
+	editCmd.Flags().String("recur", "", "New repeat rule, empty to stop repeating")
+	editCmd.Flags().StringSlice("untag", nil, "Tags to remove")
+	editCmd.Flags().IntSlice("blocked-by", nil, "Add tasks that must be done first")
+	editCmd.Flags().IntSlice("unblock", nil, "Remove tasks from the blocked-by list")
+	editCmd.Flags().Int("parent", 0, "New parent task ID, 0 to make it top-level")
+	editCmd.Flags().String("filter", "", "Query selecting the tasks to change, e.g. \"+errands\"")
+	doneCmd.Flags().Bool("cascade", false, "Also complete all subtasks")
+	listCmd.Flags().String("sort", "id", "Sort tasks by id, due, priority or manual")
+	listCmd.Flags().Bool("all", false, "Show the tasks of every list")
 }
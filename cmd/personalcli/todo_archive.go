@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// archiveFilePath returns the archive file that sits next to the tasks file.
+func archiveFilePath() string {
+	return strings.TrimSuffix(tasksFilePath, ".json") + ".archive.json"
+}
+
+// readArchive reads all archived tasks.
+func readArchive() ([]Task, error) {
+	data, err := os.ReadFile(archiveFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Task{}, nil
+		}
+		return nil, err
+	}
+
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// writeArchive writes the archived tasks.
+func writeArchive(tasks []Task) error {
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return err
+	}
+	delete(archivedMaxIDs, archiveFilePath())
+	return os.WriteFile(archiveFilePath(), data, 0644)
+}
+
+// archivedMaxIDs caches the highest task ID of each archive read so far.
+var archivedMaxIDs = map[string]int{}
+
+// archivedMaxID returns the highest ID among the archived tasks of the
+// current list, so new tasks never reuse the ID of an archived one.
+func archivedMaxID() (int, error) {
+	path := archiveFilePath()
+	if maxID, ok := archivedMaxIDs[path]; ok {
+		return maxID, nil
+	}
+	archive, err := readArchive()
+	if err != nil {
+		return 0, err
+	}
+	maxID := 0
+	for _, task := range archive {
+		maxID = max(maxID, task.ID)
+	}
+	archivedMaxIDs[path] = maxID
+	return maxID, nil
+}
+
+// parseAge parses an age such as "7d", "2w" or a Go duration like "36h".
+func parseAge(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(value, suffix)); err == nil && strings.HasSuffix(value, suffix) && n >= 0 {
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 7d, 2w or 36h)", value)
+	}
+	return d, nil
+}
+
+// archivable returns the IDs of completed tasks finished before cutoff.
+// Tasks completed before completion times were recorded always qualify. A
+// task is held back while any of its subtasks stays in the list, and a
+// subtask while its parent does, so the hierarchy is never split between the
+// two files.
+func archivable(tasks []Task, cutoff time.Time) map[int]bool {
+	ids, exists := map[int]bool{}, map[int]bool{}
+	for _, task := range tasks {
+		exists[task.ID] = true
+		if task.isDone() && (task.CompletedAt == nil || task.CompletedAt.Before(cutoff)) {
+			ids[task.ID] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, task := range tasks {
+			if task.Parent == 0 || ids[task.ID] == ids[task.Parent] || !exists[task.Parent] {
+				continue
+			}
+			delete(ids, task.ID)
+			delete(ids, task.Parent)
+			changed = true
+		}
+	}
+	return ids
+}
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Move completed tasks out of the active list into the archive",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		olderThan, _ := cmd.Flags().GetString("older-than")
+		age, err := parseAge(olderThan)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+		archive, err := readArchive()
+		if err != nil {
+			fmt.Println("Error reading archive:", err)
+			os.Exit(1)
+		}
+
+		move := archivable(tasks, currentTime().Add(-age))
+		if len(move) == 0 {
+			fmt.Println("No completed tasks to archive.")
+			return
+		}
+
+		var kept []Task
+		for _, task := range tasks {
+			if move[task.ID] {
+				archive = append(archive, task)
+				continue
+			}
+			task.BlockedBy = slices.DeleteFunc(task.BlockedBy, func(id int) bool { return move[id] })
+			kept = append(kept, task)
+		}
+		if kept == nil {
+			kept = []Task{}
+		}
+
+		if err := saveTasksAndArchive("archive", kept, archive); err != nil {
+			fmt.Println("Error writing tasks:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Archived %d completed task(s).\n", len(move))
+	},
+}
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Browse archived tasks by completion date",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		archive, err := readArchive()
+		if err != nil {
+			fmt.Println("Error reading archive:", err)
+			os.Exit(1)
+		}
+
+		now := currentTime()
+		var since time.Time
+		if sinceFlag, _ := cmd.Flags().GetString("since"); sinceFlag != "" {
+			if since, err = parseSince(sinceFlag, now); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		byDay := map[string][]Task{}
+		for _, task := range archive {
+			day := "unknown date"
+			if task.CompletedAt != nil {
+				if task.CompletedAt.Before(since) {
+					continue
+				}
+				day = task.CompletedAt.Format("2006-01-02 (Mon)")
+			} else if !since.IsZero() {
+				continue
+			}
+			byDay[day] = append(byDay[day], task)
+		}
+
+		if len(archive) == 0 {
+			fmt.Println("The archive is empty. Move finished tasks there with 'personalcli todo archive'.")
+			return
+		}
+		if len(byDay) == 0 {
+			fmt.Println("No archived tasks in that range.")
+			return
+		}
+
+		// Most recent days first; tasks without a completion time go last.
+		days := make([]string, 0, len(byDay))
+		for day := range byDay {
+			days = append(days, day)
+		}
+		sort.Slice(days, func(i, j int) bool {
+			if days[i] == "unknown date" || days[j] == "unknown date" {
+				return days[j] == "unknown date" && days[i] != days[j]
+			}
+			return days[i] > days[j]
+		})
+
+		for _, day := range days {
+			fmt.Println(day)
+			for _, task := range byDay[day] {
+				fmt.Println("  " + formatTask(task, now))
+			}
+		}
+	},
+}
+
+func init() {
+	todoCmd.AddCommand(archiveCmd)
+	todoCmd.AddCommand(logCmd)
+	archiveCmd.Flags().String("older-than", "", "Only archive tasks completed at least this long ago (e.g. 7d, 2w)")
+	logCmd.Flags().String("since", "", "Only show tasks completed since this day (today, a weekday, Nd or YYYY-MM-DD)")
+}
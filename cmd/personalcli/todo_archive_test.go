@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestArchivable(t *testing.T) {
+	old := testNow.Add(-10 * 24 * time.Hour)
+	recent := testNow.Add(-time.Hour)
+	done := func(id, parent int, at *time.Time) Task {
+		return Task{ID: id, Parent: parent, Status: statusDone, CompletedAt: at}
+	}
+	todo := func(id, parent int) Task {
+		return Task{ID: id, Parent: parent, Status: statusTodo}
+	}
+	cutoff := testNow.Add(-7 * 24 * time.Hour)
+
+	for _, tc := range []struct {
+		name  string
+		tasks []Task
+		want  []int
+	}{
+		{"done before cutoff", []Task{done(1, 0, &old), done(2, 0, &recent), todo(3, 0)}, []int{1}},
+		{"no completion time", []Task{done(1, 0, nil)}, []int{1}},
+		{"parent with open subtask", []Task{done(1, 0, &old), todo(2, 1)}, nil},
+		{"subtask of open parent", []Task{todo(1, 0), done(2, 1, &old)}, nil},
+		{"subtask done recently", []Task{done(1, 0, &old), done(2, 1, &recent)}, nil},
+		{"whole tree", []Task{done(1, 0, &old), done(2, 1, &old), done(3, 2, nil)}, []int{1, 2, 3}},
+		// One open task deep in the tree holds back every ancestor and
+		// their other subtasks.
+		{"open grandchild", []Task{done(1, 0, &old), done(2, 1, &old), todo(3, 2), done(4, 1, &old)}, nil},
+		{"missing parent", []Task{done(2, 9, &old)}, []int{2}},
+	} {
+		var got []int
+		for id := range archivable(tc.tasks, cutoff) {
+			got = append(got, id)
+		}
+		slices.Sort(got)
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s: archivable = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestNextTaskIDSkipsArchived(t *testing.T) {
+	useTempTasks(t)
+	if got := nextTaskID([]Task{{ID: 2}}); got != 3 {
+		t.Errorf("nextTaskID without archive = %d, want 3", got)
+	}
+	if err := writeArchive([]Task{{ID: 7}, {ID: 5}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := nextTaskID([]Task{{ID: 2}}); got != 8 {
+		t.Errorf("nextTaskID after archiving 7 = %d, want 8", got)
+	}
+	if got := nextTaskID([]Task{{ID: 9}}); got != 10 {
+		t.Errorf("nextTaskID with a higher active ID = %d, want 10", got)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// boardGap separates adjacent board columns.
+const boardGap = "  "
+
+// minColumnWidth keeps board columns readable on narrow terminals; the
+// board is allowed to overflow rather than squash cards further.
+const minColumnWidth = 14
+
+// boardWidth picks the width to render the board at: the --width flag, then
+// $COLUMNS, then the size of the terminal, falling back to 80.
+func boardWidth(flag int) int {
+	if flag > 0 {
+		return flag
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	if n, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && n > 0 {
+		return n
+	}
+	return 80
+}
+
+// wrapText breaks text into lines of at most width runes, splitting long
+// words when they do not fit on a line of their own.
+func wrapText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		for utf8.RuneCountInString(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:width]))
+			word = string(runes[width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// padRight pads s with spaces to width runes.
+func padRight(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// renderBoard lays the tasks out in one column per workflow state, side by
+// side, fitting the columns into width.
+func renderBoard(tasks []Task, statuses []string, width int) []string {
+	colWidth := max((width-len(boardGap)*(len(statuses)-1))/len(statuses), minColumnWidth)
+
+	columns := make([][]string, len(statuses))
+	height := 0
+	for i, status := range statuses {
+		var cards []Task
+		for _, task := range tasks {
+			if task.Status == status {
+				cards = append(cards, task)
+			}
+		}
+		title := fmt.Sprintf("%s (%d)", strings.ToUpper(status), len(cards))
+		column := []string{title, strings.Repeat("─", colWidth)}
+		for _, task := range cards {
+			text := fmt.Sprintf("#%d %s", task.ID, task.Description)
+			if task.Priority != "" {
+				text += " (" + task.Priority + ")"
+			}
+			column = append(column, wrapText(text, colWidth)...)
+		}
+		columns[i] = column
+		height = max(height, len(column))
+	}
+
+	lines := make([]string, height)
+	for row := range lines {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			if row < len(column) {
+				cells[i] = padRight(column[row], colWidth)
+			} else {
+				cells[i] = strings.Repeat(" ", colWidth)
+			}
+		}
+		lines[row] = strings.TrimRight(strings.Join(cells, boardGap), " ")
+	}
+	return lines
+}
+
+var moveCmd = &cobra.Command{
+	Use:   "move [task_id] [status]",
+	Short: "Move a task to another workflow state (backlog, todo, doing, blocked, done)",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		taskID, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Println("Invalid task ID. Please provide a number.")
+			os.Exit(1)
+		}
+		status, err := parseStatus(args[1])
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+
+		taskIndex := findTask(tasks, taskID)
+		if taskIndex == -1 {
+			fmt.Println("Task ID not found.")
+			os.Exit(1)
+		}
+		if tasks[taskIndex].Status == status {
+			fmt.Printf("Task %d is already in %s.\n", taskID, status)
+			return
+		}
+
+		// Moving to done is the same as "todo done"; moving out of done reopens.
+		var next *Task
+		switch {
+		case status == statusDone:
+			if open := openChildren(tasks, taskID); len(open) > 0 {
+				fmt.Printf("Task %d has %d open subtask(s). Finish them first.\n", taskID, len(open))
+				os.Exit(1)
+			}
+			tasks[taskIndex].complete(time.Now())
+			if tasks, next, err = scheduleNext(tasks, taskIndex, currentTime()); err != nil {
+				fmt.Println("Error scheduling next occurrence:", err)
+				os.Exit(1)
+			}
+		case tasks[taskIndex].isDone():
+			tasks[taskIndex].reopen()
+			tasks[taskIndex].Status = status
+		default:
+			tasks[taskIndex].Status = status
+		}
+
+		if err := saveTasks(fmt.Sprintf("move %d %s", taskID, status), tasks); err != nil {
+			fmt.Println("Error writing tasks:", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Moved task %d to %s.\n", taskID, status)
+		if next != nil {
+			fmt.Printf("Next occurrence is task %d, due %s.\n", next.ID, formatDue(*next.Due))
+		}
+	},
+}
+
+var boardCmd = &cobra.Command{
+	Use:   "board [query]",
+	Short: "Show tasks as a kanban board with one column per workflow state",
+	Long: `Show tasks as a kanban board with columns for backlog, todo, doing, blocked
+and done, sized to the terminal width. An optional query filters the cards
+the same way as "todo list".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		queryText := strings.Join(args, " ")
+		query, err := parseQuery(queryText, currentTime())
+		if err != nil {
+			fmt.Println("Error in query:", err)
+			if qerr, ok := err.(*queryError); ok {
+				fmt.Println(qerr.explain(queryText))
+			}
+			os.Exit(1)
+		}
+
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+
+		var visible []Task
+		for _, task := range tasks {
+			if query.matches(task) {
+				visible = append(visible, task)
+			}
+		}
+		if err := sortTasks(visible, "priority"); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		statuses := taskStatuses
+		if hideDone, _ := cmd.Flags().GetBool("hide-done"); hideDone {
+			statuses = statuses[:len(statuses)-1]
+		}
+		widthFlag, _ := cmd.Flags().GetInt("width")
+		for _, line := range renderBoard(visible, statuses, boardWidth(widthFlag)) {
+			fmt.Println(line)
+		}
+	},
+}
+
+func init() {
+	todoCmd.AddCommand(moveCmd)
+	todoCmd.AddCommand(boardCmd)
+	boardCmd.Flags().Int("width", 0, "Board width in columns (default: terminal width)")
+	boardCmd.Flags().Bool("hide-done", false, "Leave out the done column")
+}
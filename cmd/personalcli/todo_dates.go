@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nowEnvVar overrides the current time for the todo commands, so relative
+// dates such as "tomorrow" resolve the same way on every run. It takes an
+// RFC 3339 timestamp or a YYYY-MM-DD date.
+const nowEnvVar = "PERSONALCLI_NOW"
+
+// dueTimeLayout is used to display due dates that carry a time of day.
+const dueTimeLayout = "2006-01-02 15:04"
+
+// currentTime returns the time relative dates are resolved against: the
+// value of PERSONALCLI_NOW if it is set, otherwise the wall clock. Times
+// that record when something happened use time.Now instead.
+func currentTime() time.Time {
+	value := os.Getenv(nowEnvVar)
+	if value == "" {
+		return time.Now()
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Local()
+	}
+	if t, err := time.ParseInLocation(dueDateLayout, value, time.Local); err == nil {
+		return t
+	}
+	fmt.Fprintf(os.Stderr, "Warning: ignoring invalid %s %q (use RFC 3339 or YYYY-MM-DD).\n", nowEnvVar, value)
+	return time.Now()
+}
+
+// parseDate resolves a human date expression relative to now. It accepts
+//
+//	today, tomorrow, yesterday
+//	a weekday ("fri", "friday"): the next one, counting today
+//	next <weekday>: the next one after today; next week, next month
+//	last <weekday>: the last one before today; last week, last month
+//	in N days|weeks|months|years, N days|weeks|months|years ago
+//	eod, eow (Sunday), eom, eoy: the end of the day, week, month or year
+//	YYYY-MM-DD
+//
+// optionally followed by a time of day such as "5pm", "9:30am" or "17:00"
+// (with or without "at"). A time on its own means today. Without a time the
+// result is local midnight.
+func parseDate(value string, now time.Time) (time.Time, error) {
+	return resolveDate(value, now, false)
+}
+
+// parsePastDate is parseDate for looking back, as in "report --since": a
+// bare weekday means the most recent one, counting today.
+func parsePastDate(value string, now time.Time) (time.Time, error) {
+	return resolveDate(value, now, true)
+}
+
+// resolveDate implements parseDate and parsePastDate.
+func resolveDate(value string, now time.Time, past bool) (time.Time, error) {
+	words := strings.Fields(strings.ToLower(value))
+	if len(words) == 0 {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+	invalid := fmt.Errorf("invalid date %q (try today, tomorrow 5pm, next fri, in 3 days, eom or YYYY-MM-DD)", value)
+
+	hour, minute, hasTime := 0, 0, false
+	if h, m, ok := parseClock(words[len(words)-1]); ok {
+		hour, minute, hasTime = h, m, true
+		words = words[:len(words)-1]
+		if n := len(words); n > 0 && words[n-1] == "at" {
+			words = words[:n-1]
+		}
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	day, ok := resolveDay(words, today, past)
+	if !ok {
+		return time.Time{}, invalid
+	}
+	if hasTime {
+		day = time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+	}
+	return day, nil
+}
+
+// resolveDay handles the date part of a date expression, returning a local
+// midnight. A bare weekday is looked for in the past when past is set.
+func resolveDay(words []string, today time.Time, past bool) (time.Time, bool) {
+	switch len(words) {
+	case 0:
+		return today, true
+	case 1:
+		switch words[0] {
+		case "today", "eod":
+			return today, true
+		case "tomorrow", "tmr":
+			return today.AddDate(0, 0, 1), true
+		case "yesterday":
+			return today.AddDate(0, 0, -1), true
+		case "eow":
+			return today.AddDate(0, 0, (7-int(today.Weekday()))%7), true
+		case "eom":
+			return time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, today.Location()), true
+		case "eoy":
+			return time.Date(today.Year(), time.December, 31, 0, 0, 0, 0, today.Location()), true
+		}
+		if wd, ok := parseWeekday(words[0]); ok {
+			if past {
+				return today.AddDate(0, 0, -((int(today.Weekday()) - int(wd) + 7) % 7)), true
+			}
+			return today.AddDate(0, 0, (int(wd)-int(today.Weekday())+7)%7), true
+		}
+		if t, err := time.ParseInLocation(dueDateLayout, words[0], today.Location()); err == nil {
+			return t, true
+		}
+	case 2:
+		sign := 1
+		switch words[0] {
+		case "next":
+		case "last":
+			sign = -1
+		default:
+			return time.Time{}, false
+		}
+		switch words[1] {
+		case "week":
+			return today.AddDate(0, 0, 7*sign), true
+		case "month":
+			return today.AddDate(0, sign, 0), true
+		case "year":
+			return today.AddDate(sign, 0, 0), true
+		}
+		if wd, ok := parseWeekday(words[1]); ok {
+			days := (sign*(int(wd)-int(today.Weekday())) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+			return today.AddDate(0, 0, sign*days), true
+		}
+	case 3:
+		// "in N units" or "N units ago".
+		count, unit, sign := words[1], words[2], 1
+		if words[2] == "ago" {
+			count, unit, sign = words[0], words[1], -1
+		} else if words[0] != "in" {
+			break
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 0 {
+			break
+		}
+		n *= sign
+		switch strings.TrimSuffix(unit, "s") {
+		case "day":
+			return today.AddDate(0, 0, n), true
+		case "week":
+			return today.AddDate(0, 0, 7*n), true
+		case "month":
+			return today.AddDate(0, n, 0), true
+		case "year":
+			return today.AddDate(n, 0, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseClock parses a time of day such as "5pm", "9:30am", "17:00" or "noon".
+func parseClock(word string) (hour, minute int, ok bool) {
+	switch word {
+	case "noon":
+		return 12, 0, true
+	case "midnight":
+		return 0, 0, true
+	}
+
+	suffix := ""
+	if strings.HasSuffix(word, "am") || strings.HasSuffix(word, "pm") {
+		suffix = word[len(word)-2:]
+		word = word[:len(word)-2]
+	}
+	hourText, minuteText, hasMinutes := strings.Cut(word, ":")
+	if suffix == "" && !hasMinutes {
+		// A bare number is not a time; "in 3 days" must keep its 3.
+		return 0, 0, false
+	}
+	hour, err := strconv.Atoi(hourText)
+	if err != nil {
+		return 0, 0, false
+	}
+	if hasMinutes {
+		if len(minuteText) != 2 {
+			return 0, 0, false
+		}
+		if minute, err = strconv.Atoi(minuteText); err != nil || minute > 59 {
+			return 0, 0, false
+		}
+	}
+
+	switch suffix {
+	case "":
+		if hour > 23 {
+			return 0, 0, false
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		hour %= 12
+		if suffix == "pm" {
+			hour += 12
+		}
+	}
+	return hour, minute, true
+}
+
+// formatDue renders a due date, including the time of day when one was set.
+func formatDue(due time.Time) string {
+	if due.Hour() == 0 && due.Minute() == 0 {
+		return due.Format(dueDateLayout)
+	}
+	return due.Format(dueTimeLayout)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// testNow is a Wednesday morning that relative dates in tests resolve
+// against.
+var testNow = time.Date(2026, 10, 14, 10, 30, 0, 0, time.UTC)
+
+func TestParseDate(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"today", "2026-10-14 00:00"},
+		{"Tomorrow", "2026-10-15 00:00"},
+		{"yesterday", "2026-10-13 00:00"},
+		{"wed", "2026-10-14 00:00"},
+		{"friday", "2026-10-16 00:00"},
+		{"mon", "2026-10-19 00:00"},
+		{"next wed", "2026-10-21 00:00"},
+		{"next fri", "2026-10-16 00:00"},
+		{"last fri", "2026-10-09 00:00"},
+		{"last wed", "2026-10-07 00:00"},
+		{"next week", "2026-10-21 00:00"},
+		{"next month", "2026-11-14 00:00"},
+		{"last year", "2025-10-14 00:00"},
+		{"in 3 days", "2026-10-17 00:00"},
+		{"in 1 week", "2026-10-21 00:00"},
+		{"in 2 months", "2026-12-14 00:00"},
+		{"3 days ago", "2026-10-11 00:00"},
+		{"2 weeks ago", "2026-09-30 00:00"},
+		{"eod", "2026-10-14 00:00"},
+		{"eow", "2026-10-18 00:00"},
+		{"eom", "2026-10-31 00:00"},
+		{"eoy", "2026-12-31 00:00"},
+		{"2026-11-02", "2026-11-02 00:00"},
+		{"tomorrow 5pm", "2026-10-15 17:00"},
+		{"fri at 9:30am", "2026-10-16 09:30"},
+		{"17:45", "2026-10-14 17:45"},
+		{"noon", "2026-10-14 12:00"},
+		{"12am", "2026-10-14 00:00"},
+		{"in 3 days 8pm", "2026-10-17 20:00"},
+	} {
+		got, err := parseDate(tc.in, testNow)
+		if err != nil {
+			t.Errorf("parseDate(%q): %v", tc.in, err)
+			continue
+		}
+		if s := got.Format(dueTimeLayout); s != tc.want {
+			t.Errorf("parseDate(%q) = %s, want %s", tc.in, s, tc.want)
+		}
+	}
+}
+
+func TestParseDateRejects(t *testing.T) {
+	for _, in := range []string{"", "someday", "in days", "in -3 days", "-3 days ago", "next", "next decade",
+		"2026-13-01", "25:00", "13pm", "9:5", "tomorrow at", "3"} {
+		if got, err := parseDate(in, testNow); err == nil {
+			t.Errorf("parseDate(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"", "2026-10-14 00:00"},
+		{"today", "2026-10-14 00:00"},
+		// Weekdays look back, counting today.
+		{"wed", "2026-10-14 00:00"},
+		{"monday", "2026-10-12 00:00"},
+		{"thu", "2026-10-08 00:00"},
+		{"7d", "2026-10-07 00:00"},
+		{"0d", "2026-10-14 00:00"},
+		{"last week", "2026-10-07 00:00"},
+		{"3 days ago", "2026-10-11 00:00"},
+		{"eom", "2026-10-31 00:00"},
+		{"next fri", "2026-10-16 00:00"},
+		{"in 3 days", "2026-10-17 00:00"},
+		{"yesterday 5pm", "2026-10-13 17:00"},
+		{"2026-10-01", "2026-10-01 00:00"},
+	} {
+		got, err := parseSince(tc.in, testNow)
+		if err != nil {
+			t.Errorf("parseSince(%q): %v", tc.in, err)
+			continue
+		}
+		if s := got.Format(dueTimeLayout); s != tc.want {
+			t.Errorf("parseSince(%q) = %s, want %s", tc.in, s, tc.want)
+		}
+	}
+	if _, err := parseSince("-3d", testNow); err == nil {
+		t.Error("parseSince(\"-3d\") succeeded, want an error")
+	}
+}
+
+func TestCurrentTime(t *testing.T) {
+	t.Setenv(nowEnvVar, "2026-10-14T10:30:00Z")
+	if got := currentTime(); !got.Equal(testNow) {
+		t.Errorf("currentTime() = %v, want %v", got, testNow)
+	}
+
+	t.Setenv(nowEnvVar, "2026-10-14")
+	want := time.Date(2026, 10, 14, 0, 0, 0, 0, time.Local)
+	if got := currentTime(); !got.Equal(want) {
+		t.Errorf("currentTime() = %v, want %v", got, want)
+	}
+}
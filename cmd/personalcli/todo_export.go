@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export your tasks in another tool's format",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" && output != "-" {
+			f, err := os.Create(output)
+			if err != nil {
+				fmt.Println("Error creating output file:", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		switch format {
+		case "todotxt":
+			err = writeTodoTxt(w, tasks)
+		case "taskwarrior":
+			err = writeTaskwarrior(w, tasks)
+		default:
+			fmt.Printf("Error: unsupported format %q (use todotxt or taskwarrior)\n", format)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Println("Error exporting tasks:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import tasks from another tool's format ('-' reads stdin)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+
+		var r io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				fmt.Println("Error opening import file:", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+
+		added, updated := 0, 0
+		switch format {
+		case "todotxt":
+			var imported []Task
+			imported, err = readTodoTxt(r)
+			// Imported tasks get fresh IDs after the existing ones.
+			for _, task := range imported {
+				task.ID = nextTaskID(tasks)
+				tasks = append(tasks, task)
+			}
+			added = len(imported)
+		case "taskwarrior":
+			tasks, added, updated, err = mergeTaskwarrior(r, tasks)
+		default:
+			fmt.Printf("Error: unsupported format %q (use todotxt or taskwarrior)\n", format)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Println("Error parsing import file:", err)
+			os.Exit(1)
+		}
+
+		if err := saveTasks("import "+args[0], tasks); err != nil {
+			fmt.Println("Error writing tasks:", err)
+			os.Exit(1)
+		}
+		if updated > 0 {
+			fmt.Printf("Imported %d task(s) and updated %d existing task(s).\n", added, updated)
+		} else {
+			fmt.Printf("Imported %d task(s).\n", added)
+		}
+	},
+}
+
+func init() {
+	todoCmd.AddCommand(exportCmd)
+	todoCmd.AddCommand(importCmd)
+	exportCmd.Flags().String("format", "todotxt", "Output format: todotxt or taskwarrior")
+	exportCmd.Flags().StringP("output", "o", "", "Write to a file instead of stdout")
+	importCmd.Flags().String("format", "todotxt", "Input format: todotxt or taskwarrior (output of 'task export')")
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// journalMaxSize is the size at which the journal is rotated: the oldest
+// entries are dropped, keeping the last journalKeep.
+const (
+	journalMaxSize = 4 << 20
+	journalKeep    = 200
+)
+
+// taskOperation is one entry in the append-only operation journal. Mutations
+// record how to revert the task list, and the archive when they changed it
+// too; undo entries point back at the mutation they reverted.
+type taskOperation struct {
+	Seq     int       `json:"seq"`
+	Time    time.Time `json:"time"`
+	Op      string    `json:"op"`
+	Tasks   *taskDiff `json:"tasks,omitempty"`
+	Archive *taskDiff `json:"archive,omitempty"`
+	Undoes  int       `json:"undoes,omitempty"`
+}
+
+// taskDiff records how to turn a task list back into an earlier version of
+// it, keeping only what a change touched.
+type taskDiff struct {
+	// Changed holds the earlier versions of tasks that were changed or
+	// removed, and Added the IDs of tasks that did not exist yet.
+	Changed []Task `json:"changed,omitempty"`
+	Added   []int  `json:"added,omitempty"`
+	// Order is the earlier order of the task IDs, when it changed.
+	Order []int `json:"order,omitempty"`
+}
+
+// diffTasks returns the diff that turns after back into before.
+func diffTasks(before, after []Task) taskDiff {
+	var diff taskDiff
+	current := map[int][]byte{}
+	for _, task := range after {
+		current[task.ID], _ = json.Marshal(task)
+	}
+	var kept []int
+	for _, task := range before {
+		data, _ := json.Marshal(task)
+		if prev, ok := current[task.ID]; !ok || !bytes.Equal(prev, data) {
+			diff.Changed = append(diff.Changed, task)
+		}
+		if _, ok := current[task.ID]; ok {
+			kept = append(kept, task.ID)
+		}
+	}
+
+	existed := map[int]bool{}
+	for _, task := range before {
+		existed[task.ID] = true
+	}
+	var remaining []int
+	for _, task := range after {
+		if !existed[task.ID] {
+			diff.Added = append(diff.Added, task.ID)
+		} else {
+			remaining = append(remaining, task.ID)
+		}
+	}
+	// Removed or reordered tasks need the old order to go back in place.
+	if len(kept) != len(before) || !slices.Equal(kept, remaining) {
+		for _, task := range before {
+			diff.Order = append(diff.Order, task.ID)
+		}
+	}
+	return diff
+}
+
+// revert applies the diff to the task list it was taken against.
+func (d taskDiff) revert(tasks []Task) []Task {
+	drop := map[int]bool{}
+	for _, id := range d.Added {
+		drop[id] = true
+	}
+	old := map[int]Task{}
+	for _, task := range d.Changed {
+		old[task.ID] = task
+	}
+
+	reverted := []Task{}
+	for _, task := range tasks {
+		if drop[task.ID] {
+			continue
+		}
+		if prev, ok := old[task.ID]; ok {
+			task = prev
+			delete(old, task.ID)
+		}
+		reverted = append(reverted, task)
+	}
+	for _, task := range d.Changed {
+		if _, removed := old[task.ID]; removed {
+			reverted = append(reverted, task)
+		}
+	}
+
+	if d.Order != nil {
+		// Tasks missing from the old order, if any, go last.
+		position := map[int]int{}
+		for _, task := range reverted {
+			position[task.ID] = len(d.Order)
+		}
+		for i, id := range d.Order {
+			position[id] = i
+		}
+		sort.SliceStable(reverted, func(i, j int) bool {
+			return position[reverted[i].ID] < position[reverted[j].ID]
+		})
+	}
+	return reverted
+}
+
+// journalFilePath returns the journal file that sits next to the tasks file.
+func journalFilePath() string {
+	return strings.TrimSuffix(tasksFilePath, ".json") + ".journal"
+}
+
+// readJournal reads every entry of the operation journal in order.
+func readJournal() ([]taskOperation, error) {
+	f, err := os.Open(journalFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var ops []taskOperation
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var op taskOperation
+		if err := json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			return nil, fmt.Errorf("corrupt journal entry: %v", err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, scanner.Err()
+}
+
+// lastJournalSeq returns the sequence number of the last journal entry,
+// reading only the last line of the file.
+func lastJournalSeq(f *os.File, size int64) (int, error) {
+	// Find the start of the last line, skipping the trailing newline.
+	end := size - 1
+	start := int64(0)
+	buf := make([]byte, 4096)
+	for pos := end; pos > 0 && start == 0; {
+		n := min(pos, int64(len(buf)))
+		pos -= n
+		if _, err := f.ReadAt(buf[:n], pos); err != nil {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i != -1 {
+			start = pos + int64(i) + 1
+		}
+	}
+
+	var last struct {
+		Seq int `json:"seq"`
+	}
+	if err := json.NewDecoder(io.NewSectionReader(f, start, size-start)).Decode(&last); err != nil {
+		return 0, fmt.Errorf("corrupt journal entry: %v", err)
+	}
+	return last.Seq, nil
+}
+
+// appendJournal adds an entry to the end of the operation journal, rotating
+// the journal once it grows past journalMaxSize.
+func appendJournal(op taskOperation) error {
+	f, err := os.OpenFile(journalFilePath(), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > 0 {
+		if op.Seq, err = lastJournalSeq(f, info.Size()); err != nil {
+			return err
+		}
+	}
+	op.Seq++
+	op.Time = time.Now()
+
+	data, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if info.Size()+int64(len(data)) > journalMaxSize {
+		return rotateJournal()
+	}
+	return nil
+}
+
+// rotateJournal drops all but the last journalKeep entries.
+func rotateJournal() error {
+	ops, err := readJournal()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, op := range ops[max(0, len(ops)-journalKeep):] {
+		data, err := json.Marshal(op)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	}
+	tmp := journalFilePath() + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, journalFilePath())
+}
+
+// saveTasks journals how to revert the change from the current contents of
+// the tasks file to tasks under the given operation name and then writes
+// tasks, so the change can be undone. Tasks that changed get a fresh
+// ModifiedAt.
+func saveTasks(op string, tasks []Task) error {
+	before, err := readTasks()
+	if err != nil {
+		return err
+	}
+	stampModified(before, tasks, time.Now())
+	diff := diffTasks(before, tasks)
+	if err := appendJournal(taskOperation{Op: op, Tasks: &diff}); err != nil {
+		return fmt.Errorf("recording operation: %v", err)
+	}
+	return writeTasks(tasks)
+}
+
+// saveTasksAndArchive is saveTasks for operations that also change the
+// archive; both files are journaled so undo restores them together.
+func saveTasksAndArchive(op string, tasks, archive []Task) error {
+	before, err := readTasks()
+	if err != nil {
+		return err
+	}
+	archiveBefore, err := readArchive()
+	if err != nil {
+		return err
+	}
+	stampModified(before, tasks, time.Now())
+	diff, archiveDiff := diffTasks(before, tasks), diffTasks(archiveBefore, archive)
+	if err := appendJournal(taskOperation{Op: op, Tasks: &diff, Archive: &archiveDiff}); err != nil {
+		return fmt.Errorf("recording operation: %v", err)
+	}
+	if err := writeArchive(archive); err != nil {
+		return err
+	}
+	return writeTasks(tasks)
+}
+
+// lastUndoable returns the most recent mutation that has not been undone yet.
+func lastUndoable(ops []taskOperation) (taskOperation, bool) {
+	var stack []taskOperation
+	for _, op := range ops {
+		if op.Undoes != 0 {
+			// Rotation may have dropped the entry an undo refers to.
+			if n := len(stack); n > 0 && stack[n-1].Seq == op.Undoes {
+				stack = stack[:n-1]
+			}
+			continue
+		}
+		stack = append(stack, op)
+	}
+	if len(stack) == 0 {
+		return taskOperation{}, false
+	}
+	return stack[len(stack)-1], true
+}
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert the last change to your todo list",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ops, err := readJournal()
+		if err != nil {
+			fmt.Println("Error reading journal:", err)
+			os.Exit(1)
+		}
+
+		op, ok := lastUndoable(ops)
+		if !ok {
+			fmt.Println("Nothing to undo.")
+			return
+		}
+
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+		archive, err := readArchive()
+		if err != nil {
+			fmt.Println("Error reading archive:", err)
+			os.Exit(1)
+		}
+		restored := op.Tasks.revert(tasks)
+
+		if err := appendJournal(taskOperation{Op: "undo " + op.Op, Undoes: op.Seq}); err != nil {
+			fmt.Println("Error recording undo:", err)
+			os.Exit(1)
+		}
+		if op.Archive != nil {
+			if err := writeArchive(op.Archive.revert(archive)); err != nil {
+				fmt.Println("Error writing archive:", err)
+				os.Exit(1)
+			}
+		}
+		if err := writeTasks(restored); err != nil {
+			fmt.Println("Error writing tasks:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Undid \"%s\" from %s.\n", op.Op, op.Time.Format("2006-01-02 15:04"))
+	},
+}
+
+func init() {
+	todoCmd.AddCommand(undoCmd)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// taskJSON renders tasks the way they are stored, for comparing lists.
+func taskJSON(t *testing.T, tasks []Task) string {
+	t.Helper()
+	data, err := json.Marshal(tasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestDiffTasksRevert(t *testing.T) {
+	a := Task{ID: 1, Description: "a", Status: statusTodo}
+	b := Task{ID: 2, Description: "b", Status: statusTodo}
+	c := Task{ID: 3, Description: "c", Status: statusTodo}
+	bDone := b
+	bDone.Status = statusDone
+	d := Task{ID: 4, Description: "d", Status: statusTodo}
+
+	for _, tc := range []struct {
+		name          string
+		before, after []Task
+		changed       int
+		added         int
+		order         bool
+	}{
+		{"unchanged", []Task{a, b}, []Task{a, b}, 0, 0, false},
+		{"changed", []Task{a, b, c}, []Task{a, bDone, c}, 1, 0, false},
+		{"added", []Task{a, b}, []Task{a, b, d}, 0, 1, false},
+		{"removed", []Task{a, b, c}, []Task{a, c}, 1, 0, true},
+		{"reordered", []Task{a, b, c}, []Task{c, a, b}, 0, 0, true},
+		{"everything", []Task{a, b, c}, []Task{d, c, bDone}, 2, 1, true},
+		{"emptied", []Task{a, b}, []Task{}, 2, 0, true},
+		{"from empty", []Task{}, []Task{a, b}, 0, 2, false},
+	} {
+		diff := diffTasks(tc.before, tc.after)
+		if len(diff.Changed) != tc.changed || len(diff.Added) != tc.added || (diff.Order != nil) != tc.order {
+			t.Errorf("%s: diff = %+v", tc.name, diff)
+		}
+		if got, want := taskJSON(t, diff.revert(tc.after)), taskJSON(t, tc.before); got != want {
+			t.Errorf("%s: reverted to %s, want %s", tc.name, got, want)
+		}
+	}
+}
+
+func TestUndo(t *testing.T) {
+	useTempTasks(t)
+	a := Task{ID: 1, Description: "a", Status: statusTodo}
+	b := Task{ID: 2, Description: "b", Status: statusDone}
+	if err := writeTasks([]Task{a, b}); err != nil {
+		t.Fatal(err)
+	}
+	current := func() (string, string) {
+		tasks, err := readTasks()
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive, err := readArchive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return taskJSON(t, tasks), taskJSON(t, archive)
+	}
+	start, _ := current()
+
+	c := Task{ID: 3, Description: "c", Status: statusTodo}
+	if err := saveTasks("add 3", []Task{a, b, c}); err != nil {
+		t.Fatal(err)
+	}
+	added, _ := current()
+	if err := saveTasksAndArchive("archive", []Task{a, c}, []Task{b}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Undo walks back one operation at a time, restoring the archive along
+	// with the tasks, and ignores operations that were already undone.
+	undoCmd.Run(undoCmd, nil)
+	tasks, archive := current()
+	if tasks != added || archive != "[]" {
+		t.Errorf("after undoing the archive: tasks %s, archive %s", tasks, archive)
+	}
+	undoCmd.Run(undoCmd, nil)
+	if tasks, _ = current(); tasks != start {
+		t.Errorf("after undoing the add: tasks %s, want %s", tasks, start)
+	}
+	undoCmd.Run(undoCmd, nil)
+	if tasks, _ = current(); tasks != start {
+		t.Errorf("undo with nothing left changed the tasks to %s", tasks)
+	}
+
+	ops, err := readJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 4 || ops[2].Undoes != ops[1].Seq || ops[3].Undoes != ops[0].Seq {
+		t.Errorf("journal = %+v", ops)
+	}
+}
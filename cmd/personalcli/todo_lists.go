@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultListName is the list stored in tasks.json. Other lists live next to
+// it as tasks-<name>.json, each with its own archive and undo journal.
+const defaultListName = "default"
+
+// listEnvVar selects the list to use when --list is not given.
+const listEnvVar = "PERSONALCLI_LIST"
+
+// currentList is the name of the list tasksFilePath points at.
+var currentList = defaultListName
+
+var listNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// defaultListFilePath returns the file recording which list commands use
+// when none is given.
+func defaultListFilePath() string {
+	return filepath.Join(tasksDir, "default_list")
+}
+
+// validateListName checks that a list name is usable as part of a file name.
+func validateListName(name string) error {
+	if !listNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid list name %q (use lowercase letters, digits, - and _)", name)
+	}
+	return nil
+}
+
+// listFilePath returns the tasks file for the named list.
+func listFilePath(name string) string {
+	if name == defaultListName {
+		return filepath.Join(tasksDir, "tasks.json")
+	}
+	return filepath.Join(tasksDir, "tasks-"+name+".json")
+}
+
+// useList points readTasks, saveTasks and friends at the named list.
+func useList(name string) {
+	currentList = name
+	tasksFilePath = listFilePath(name)
+}
+
+// configuredDefaultList returns the list to use when --list is not given:
+// $PERSONALCLI_LIST, then the one saved with "todo lists --default", then
+// the default list.
+func configuredDefaultList() (string, error) {
+	if name := os.Getenv(listEnvVar); name != "" {
+		return name, nil
+	}
+	data, err := os.ReadFile(defaultListFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return defaultListName, nil
+		}
+		return "", err
+	}
+	if name := strings.TrimSpace(string(data)); name != "" {
+		return name, nil
+	}
+	return defaultListName, nil
+}
+
+// useListFlag points the task commands at the list named by the command's
+// --list flag, or else the configured default list. Commands outside "todo"
+// that work on tasks use it as their PreRunE.
+func useListFlag(cmd *cobra.Command, args []string) error {
+	name, _ := cmd.Flags().GetString("list")
+	if name == "" {
+		var err error
+		if name, err = configuredDefaultList(); err != nil {
+			return fmt.Errorf("reading default list: %v", err)
+		}
+	}
+	if err := validateListName(name); err != nil {
+		return err
+	}
+	useList(name)
+	return nil
+}
+
+// todoLists returns the names of all lists that have a tasks file, with the
+// default list first and the rest in alphabetical order.
+func todoLists() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(tasksDir, "tasks-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "tasks-"), ".json")
+		// Archives such as tasks-work.archive.json are not lists themselves.
+		if validateListName(name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{defaultListName}, names...), nil
+}
+
+// transferTasks moves the tasks with the given IDs, along with their
+// subtasks, from one list to another. Moved tasks get fresh IDs in the target
+// list; parent and blocked-by links between moved tasks are kept, links to
+// tasks left behind are dropped.
+func transferTasks(source, target []Task, ids []int) (kept, grown []Task, moved map[int]int) {
+	moving := map[int]bool{}
+	for _, id := range ids {
+		moving[id] = true
+		for _, child := range descendants(source, id) {
+			moving[child] = true
+		}
+	}
+
+	moved = map[int]int{}
+	nextID := nextTaskID(target)
+	for _, task := range source {
+		if moving[task.ID] {
+			moved[task.ID] = nextID
+			nextID++
+		}
+	}
+
+	grown = target
+	for _, task := range source {
+		if !moving[task.ID] {
+			task.BlockedBy = slices.DeleteFunc(task.BlockedBy, func(id int) bool { return moving[id] })
+			kept = append(kept, task)
+			continue
+		}
+		task.ID = moved[task.ID]
+		task.Parent = moved[task.Parent]
+		var blockedBy []int
+		for _, id := range task.BlockedBy {
+			if newID, ok := moved[id]; ok {
+				blockedBy = append(blockedBy, newID)
+			}
+		}
+		task.BlockedBy = blockedBy
+		grown = append(grown, task)
+	}
+	if kept == nil {
+		kept = []Task{}
+	}
+	return kept, grown, moved
+}
+
+var listsCmd = &cobra.Command{
+	Use:   "lists",
+	Short: "Show your todo lists and set the default one",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cmd.Flags().Changed("default") {
+			name, _ := cmd.Flags().GetString("default")
+			if err := validateListName(name); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			if err := os.WriteFile(defaultListFilePath(), []byte(name+"\n"), 0644); err != nil {
+				fmt.Println("Error saving default list:", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Default list is now %s.\n", name)
+			return
+		}
+
+		names, err := todoLists()
+		if err != nil {
+			fmt.Println("Error finding lists:", err)
+			os.Exit(1)
+		}
+		active := currentList
+		fmt.Println("Lists:")
+		for _, name := range names {
+			useList(name)
+			tasks, err := readTasks()
+			if err != nil {
+				fmt.Printf("Error reading list %s: %v\n", name, err)
+				os.Exit(1)
+			}
+			open := 0
+			for _, task := range tasks {
+				if !task.isDone() {
+					open++
+				}
+			}
+			marker := " "
+			if name == active {
+				marker = "*"
+			}
+			fmt.Printf("%s %s: %d open, %d total\n", marker, name, open, len(tasks))
+		}
+	},
+}
+
+var transferCmd = &cobra.Command{
+	Use:   "transfer [task_id...] [list]",
+	Short: "Move tasks and their subtasks to another list",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		targetName := args[len(args)-1]
+		if err := validateListName(targetName); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		sourceName := currentList
+		if targetName == sourceName {
+			fmt.Printf("Tasks are already in list %s.\n", targetName)
+			os.Exit(1)
+		}
+
+		source, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+		var ids []int
+		for _, arg := range args[:len(args)-1] {
+			taskID, err := strconv.Atoi(arg)
+			if err != nil {
+				fmt.Printf("Invalid task ID %q. Please provide numbers.\n", arg)
+				os.Exit(1)
+			}
+			if findTask(source, taskID) == -1 {
+				fmt.Printf("Task ID %d not found.\n", taskID)
+				os.Exit(1)
+			}
+			ids = append(ids, taskID)
+		}
+
+		useList(targetName)
+		target, err := readTasks()
+		if err != nil {
+			fmt.Printf("Error reading list %s: %v\n", targetName, err)
+			os.Exit(1)
+		}
+		kept, grown, moved := transferTasks(source, target, ids)
+
+		// Each list journals its own side of the move, so undo works per list.
+		op := fmt.Sprintf("transfer %s from %s", strings.Join(args[:len(args)-1], " "), sourceName)
+		if err := saveTasks(op, grown); err != nil {
+			fmt.Println("Error writing tasks:", err)
+			os.Exit(1)
+		}
+		useList(sourceName)
+		op = fmt.Sprintf("transfer %s to %s", strings.Join(args[:len(args)-1], " "), targetName)
+		if err := saveTasks(op, kept); err != nil {
+			fmt.Println("Error writing tasks:", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Moved %d task(s) to list %s:\n", len(moved), targetName)
+		for _, task := range source {
+			if newID, ok := moved[task.ID]; ok {
+				fmt.Printf("  %d -> %d: %s\n", task.ID, newID, task.Description)
+			}
+		}
+	},
+}
+
+func init() {
+	todoCmd.PersistentFlags().StringP("list", "L", "", "Todo list to use (default: $PERSONALCLI_LIST or the saved default)")
+	todoCmd.PersistentPreRunE = useListFlag
+
+	todoCmd.AddCommand(listsCmd)
+	todoCmd.AddCommand(transferCmd)
+	listsCmd.Flags().String("default", "", "Make this list the one used when --list is not given")
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// dependencyGraph maps each open task to the open tasks it waits on. Besides
+// explicit blocked-by links, a parent waits on its open subtasks. Blockers
+// that are completed or no longer exist are ignored.
+func dependencyGraph(tasks []Task) map[int][]int {
+	open := map[int]bool{}
+	for _, task := range tasks {
+		if !task.isDone() {
+			open[task.ID] = true
+		}
+	}
+
+	graph := map[int][]int{}
+	for _, task := range tasks {
+		if !open[task.ID] {
+			continue
+		}
+		graph[task.ID] = nil
+		for _, blocker := range task.BlockedBy {
+			if open[blocker] {
+				graph[task.ID] = append(graph[task.ID], blocker)
+			}
+		}
+		if open[task.Parent] {
+			graph[task.Parent] = append(graph[task.Parent], task.ID)
+		}
+	}
+	return graph
+}
+
+// findDependencyCycles returns every loop of tasks that wait on each other,
+// each as a list of IDs in dependency order.
+func findDependencyCycles(graph map[int][]int) [][]int {
+	const (
+		unvisited = iota
+		inProgress
+		finished
+	)
+	state := map[int]int{}
+	var stack []int
+	var cycles [][]int
+
+	var visit func(id int)
+	visit = func(id int) {
+		state[id] = inProgress
+		stack = append(stack, id)
+		for _, dep := range graph[id] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case inProgress:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == dep {
+						cycles = append(cycles, append([]int(nil), stack[i:]...))
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = finished
+	}
+
+	ids := make([]int, 0, len(graph))
+	for id := range graph {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+	return cycles
+}
+
+// urgency is the sort key used by "todo next": a priority rank and an
+// optional due date, lower meaning more urgent.
+type urgency struct {
+	rank int
+	due  *time.Time
+}
+
+func (u urgency) moreUrgentThan(other urgency) bool {
+	if u.rank != other.rank {
+		return u.rank < other.rank
+	}
+	if u.due == nil || other.due == nil {
+		return u.due != nil && other.due == nil
+	}
+	return u.due.Before(*other.due)
+}
+
+// nextActions returns the open tasks that are not waiting on anything, most
+// urgent first. A task inherits the urgency of the most urgent task that
+// transitively waits on it, so a low-priority blocker of a high-priority task
+// is surfaced early. Cycles are returned so the caller can report them.
+func nextActions(tasks []Task) ([]Task, [][]int) {
+	graph := dependencyGraph(tasks)
+
+	dependents := map[int][]int{}
+	for id, deps := range graph {
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], id)
+		}
+	}
+
+	own := map[int]urgency{}
+	for _, task := range tasks {
+		own[task.ID] = urgency{rank: priorityRank(task.Priority), due: task.Due}
+	}
+
+	effective := map[int]urgency{}
+	var resolve func(id int, seen map[int]bool) urgency
+	resolve = func(id int, seen map[int]bool) urgency {
+		if u, ok := effective[id]; ok {
+			return u
+		}
+		seen[id] = true
+		best := own[id]
+		for _, dependent := range dependents[id] {
+			if seen[dependent] {
+				continue
+			}
+			if u := resolve(dependent, seen); u.moreUrgentThan(best) {
+				best = u
+			}
+		}
+		delete(seen, id)
+		effective[id] = best
+		return best
+	}
+
+	// Backlog items and tasks waiting on someone else are not actionable.
+	var ready []Task
+	for _, task := range tasks {
+		if task.Status == statusBacklog || task.Status == statusBlocked {
+			continue
+		}
+		if deps, open := graph[task.ID]; open && len(deps) == 0 {
+			resolve(task.ID, map[int]bool{})
+			ready = append(ready, task)
+		}
+	}
+
+	sort.SliceStable(ready, func(i, j int) bool {
+		a, b := ready[i], ready[j]
+		if effective[a.ID].moreUrgentThan(effective[b.ID]) {
+			return true
+		}
+		if effective[b.ID].moreUrgentThan(effective[a.ID]) {
+			return false
+		}
+		if own[a.ID].moreUrgentThan(own[b.ID]) {
+			return true
+		}
+		if own[b.ID].moreUrgentThan(own[a.ID]) {
+			return false
+		}
+		return a.ID < b.ID
+	})
+	return ready, findDependencyCycles(graph)
+}
+
+// validateBlockers checks that every blocker exists and that making id wait
+// on them would not create a dependency loop.
+func validateBlockers(tasks []Task, id int, blockers []int) error {
+	for _, blocker := range blockers {
+		if blocker == id {
+			return fmt.Errorf("task %d cannot block itself", id)
+		}
+		if findTask(tasks, blocker) == -1 {
+			return fmt.Errorf("blocking task %d not found", blocker)
+		}
+	}
+	if id == 0 {
+		return nil
+	}
+
+	trial := append([]Task(nil), tasks...)
+	i := findTask(trial, id)
+	trial[i].BlockedBy = blockers
+	for _, cycle := range findDependencyCycles(dependencyGraph(trial)) {
+		for _, member := range cycle {
+			if member == id {
+				return fmt.Errorf("dependency loop: %s", formatCycle(cycle))
+			}
+		}
+	}
+	return nil
+}
+
+// formatCycle renders a dependency loop such as "3 -> 5 -> 3".
+func formatCycle(cycle []int) string {
+	parts := make([]string, 0, len(cycle)+1)
+	for _, id := range cycle {
+		parts = append(parts, strconv.Itoa(id))
+	}
+	parts = append(parts, strconv.Itoa(cycle[0]))
+	return strings.Join(parts, " -> ")
+}
+
+var nextCmd = &cobra.Command{
+	Use:   "next",
+	Short: "Show the tasks you can work on now, most urgent first",
+	Long: `Lists open tasks whose blockers and subtasks are all done, ordered by priority
+and due date. Tasks in the backlog or blocked columns are left out. A task
+that blocks more urgent work is ranked as urgent as that work.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+
+		ready, cycles := nextActions(tasks)
+		for _, cycle := range cycles {
+			fmt.Printf("Warning: tasks wait on each other and can never start: %s\n", formatCycle(cycle))
+		}
+
+		if len(ready) == 0 {
+			fmt.Println("Nothing is actionable right now.")
+			return
+		}
+
+		limit, _ := cmd.Flags().GetInt("limit")
+		if limit > 0 && len(ready) > limit {
+			ready = ready[:limit]
+		}
+
+		now := currentTime()
+		fmt.Println("Next up:")
+		for _, task := range ready {
+			fmt.Println(formatTask(task, now))
+		}
+	},
+}
+
+func init() {
+	todoCmd.AddCommand(nextCmd)
+	nextCmd.Flags().IntP("limit", "n", 0, "Show at most this many tasks")
+}
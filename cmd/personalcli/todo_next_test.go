@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Status: statusTodo, BlockedBy: []int{2, 3, 9}},
+		{ID: 2, Status: statusTodo},
+		{ID: 3, Status: statusDone},
+		{ID: 4, Status: statusTodo, Parent: 1},
+		{ID: 5, Status: statusDone, Parent: 1},
+		{ID: 6, Status: statusDone, BlockedBy: []int{2}},
+	}
+	// Done and missing blockers drop out; a parent waits on its open
+	// subtasks; done tasks wait on nothing.
+	want := "map[1:[2 4] 2:[] 4:[]]"
+	if got := fmt.Sprint(dependencyGraph(tasks)); got != want {
+		t.Errorf("dependencyGraph = %s, want %s", got, want)
+	}
+}
+
+func TestFindDependencyCycles(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		graph map[int][]int
+		want  string
+	}{
+		{"none", map[int][]int{1: {2}, 2: {3}, 3: nil}, "[]"},
+		{"diamond", map[int][]int{1: {2, 3}, 2: {4}, 3: {4}, 4: nil}, "[]"},
+		{"self", map[int][]int{1: {1}}, "[[1]]"},
+		{"pair", map[int][]int{1: {2}, 2: {1}}, "[[1 2]]"},
+		{"loop behind a chain", map[int][]int{1: {2}, 2: {3}, 3: {4}, 4: {2}}, "[[2 3 4]]"},
+		{"two loops", map[int][]int{1: {2}, 2: {1}, 3: {4}, 4: {5}, 5: {3}}, "[[1 2] [3 4 5]]"},
+		{"shared task", map[int][]int{1: {2}, 2: {1, 3}, 3: {2}}, "[[1 2] [2 3]]"},
+	} {
+		if got := fmt.Sprint(findDependencyCycles(tc.graph)); got != tc.want {
+			t.Errorf("%s: cycles = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+
+	// A subtask blocked by its parent can never start: the parent waits
+	// on it in turn.
+	tasks := []Task{
+		{ID: 1, Status: statusTodo},
+		{ID: 2, Status: statusTodo, Parent: 1, BlockedBy: []int{1}},
+	}
+	if got := fmt.Sprint(findDependencyCycles(dependencyGraph(tasks))); got != "[[1 2]]" {
+		t.Errorf("subtask blocked by its parent: cycles = %s, want [[1 2]]", got)
+	}
+}
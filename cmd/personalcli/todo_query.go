@@ -0,0 +1,444 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The task query language used by "todo list":
+//
+//	query   = or
+//	or      = and { "or" and }
+//	and     = not { ["and"] not }
+//	not     = "not" not | "(" query ")" | term
+//	term    = +tag | @context | field op value | word | "quoted phrase"
+//
+// Fields are status (open or a workflow state such as doing), priority
+// (H, M, L, none), due (a date, none or any), project, tag, context and id.
+// The operators are ":" and "=" for equality, "!=", and "<", "<=", ">", ">="
+// for due, priority and id.
+// Bare words and quoted phrases match the description, ignoring case.
+
+// taskQuery is a compiled query that can be evaluated against tasks.
+type taskQuery interface {
+	matches(task Task) bool
+}
+
+// queryError reports a problem with a query and where it was found.
+type queryError struct {
+	pos    int
+	length int
+	msg    string
+}
+
+func (e *queryError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.msg, e.pos+1)
+}
+
+// explain renders the query with a marker under the offending token.
+func (e *queryError) explain(query string) string {
+	// The error may point just past the end of the query.
+	start := min(e.pos, len(query))
+	end := min(e.pos+e.length, len(query))
+	column := utf8.RuneCountInString(query[:start])
+	length := max(utf8.RuneCountInString(query[start:end]), 1)
+	return fmt.Sprintf("  %s\n  %s%s", query, strings.Repeat(" ", column), strings.Repeat("^", length))
+}
+
+type queryTokenKind int
+
+const (
+	tokenWord queryTokenKind = iota
+	tokenPhrase
+	tokenLParen
+	tokenRParen
+	tokenEOF
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	pos  int
+}
+
+// tokenizeQuery splits a query into words, quoted phrases and parentheses.
+func tokenizeQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	i := 0
+	for i < len(query) {
+		c, size := utf8.DecodeRuneInString(query[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case c == '(':
+			tokens = append(tokens, queryToken{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, queryToken{tokenRParen, ")", i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(query[i+1:], '"')
+			if end == -1 {
+				return nil, &queryError{i, len(query) - i, "unterminated quote"}
+			}
+			tokens = append(tokens, queryToken{tokenPhrase, query[i+1 : i+1+end], i})
+			i += end + 2
+		default:
+			start := i
+			for i < len(query) {
+				c, size := utf8.DecodeRuneInString(query[i:])
+				if unicode.IsSpace(c) || c == '(' || c == ')' || c == '"' {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, queryToken{tokenWord, query[start:i], start})
+		}
+	}
+	return append(tokens, queryToken{tokenEOF, "", len(query)}), nil
+}
+
+// queryParser is a recursive-descent parser over the token list.
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	now    time.Time
+}
+
+// parseQuery compiles a query. Relative dates such as "friday" are resolved
+// against now. An empty query matches every task.
+func parseQuery(query string, now time.Time) (taskQuery, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens, now: now}
+	if p.peek().kind == tokenEOF {
+		return matchAll{}, nil
+	}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, &queryError{tok.pos, len(tok.text), fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	return q, nil
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func isKeyword(tok queryToken, keyword string) bool {
+	return tok.kind == tokenWord && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *queryParser) parseOr() (taskQuery, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for isKeyword(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orQuery{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (taskQuery, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if isKeyword(tok, "and") {
+			p.next()
+		} else if tok.kind == tokenEOF || tok.kind == tokenRParen || isKeyword(tok, "or") {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andQuery{left, right}
+	}
+}
+
+func (p *queryParser) parseNot() (taskQuery, error) {
+	tok := p.next()
+	switch {
+	case isKeyword(tok, "not"):
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notQuery{inner}, nil
+	case tok.kind == tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, &queryError{tok.pos, 1, "unclosed \"(\""}
+		}
+		return inner, nil
+	case tok.kind == tokenRParen:
+		return nil, &queryError{tok.pos, 1, "unexpected \")\""}
+	case tok.kind == tokenEOF:
+		return nil, &queryError{tok.pos, 1, "query ends early, expected a term"}
+	case tok.kind == tokenPhrase:
+		return textQuery{strings.ToLower(tok.text)}, nil
+	case isKeyword(tok, "and") || isKeyword(tok, "or"):
+		return nil, &queryError{tok.pos, len(tok.text), fmt.Sprintf("%q needs a term before it", tok.text)}
+	}
+	return p.parseTerm(tok)
+}
+
+// queryOperators is ordered so two-character operators are found first.
+var queryOperators = []string{"<=", ">=", "!=", "<", ">", "=", ":"}
+
+// parseTerm compiles a single word such as +work, due<friday or milk.
+func (p *queryParser) parseTerm(tok queryToken) (taskQuery, error) {
+	word := tok.text
+	if len(word) > 1 && word[0] == '+' {
+		return tagQuery{word[1:]}, nil
+	}
+	if len(word) > 1 && word[0] == '@' {
+		return contextQuery{word[1:]}, nil
+	}
+
+	opAt, op := -1, ""
+	for _, candidate := range queryOperators {
+		if i := strings.Index(word, candidate); i > 0 && (opAt == -1 || i < opAt) {
+			opAt, op = i, candidate
+		}
+	}
+	if opAt == -1 {
+		return textQuery{strings.ToLower(word)}, nil
+	}
+
+	field := strings.ToLower(word[:opAt])
+	value := word[opAt+len(op):]
+	valuePos := tok.pos + opAt + len(op)
+	fail := func(msg string) error {
+		return &queryError{valuePos, len(value), msg}
+	}
+	if value == "" {
+		return nil, fail(fmt.Sprintf("missing value after %q", field+op))
+	}
+
+	// "!=" is compiled as the negation of ":".
+	negate := op == "!="
+	if op == "=" || op == "!=" {
+		op = ":"
+	}
+	ordered := op != ":"
+
+	var q taskQuery
+	switch field {
+	case "status":
+		if ordered {
+			return nil, &queryError{tok.pos + opAt, len(op), "status only supports : and !="}
+		}
+		switch strings.ToLower(value) {
+		case "open", "pending":
+			q = openQuery{}
+		case "closed":
+			q = statusQuery{statusDone}
+		default:
+			status, err := parseStatus(value)
+			if err != nil {
+				return nil, fail(fmt.Sprintf("unknown status %q (use open or %s)", value, strings.Join(taskStatuses, ", ")))
+			}
+			q = statusQuery{status}
+		}
+	case "priority", "pri":
+		rank := 3
+		if !strings.EqualFold(value, "none") {
+			priority, err := parsePriority(value)
+			if err != nil {
+				return nil, fail(err.Error())
+			}
+			rank = priorityRank(priority)
+		}
+		q = priorityQuery{op, rank}
+	case "due":
+		switch strings.ToLower(value) {
+		case "none", "any":
+			if ordered {
+				return nil, fail(fmt.Sprintf("%q cannot be compared with %s", value, op))
+			}
+			q = dueSetQuery{strings.EqualFold(value, "any")}
+		default:
+			day, err := resolveQueryDate(value, p.now)
+			if err != nil {
+				return nil, fail(err.Error())
+			}
+			q = dueQuery{op, day}
+			if negate {
+				// due!=friday still only matches tasks that have a due date.
+				return andQuery{dueSetQuery{true}, notQuery{q}}, nil
+			}
+		}
+	case "project", "proj":
+		if ordered {
+			return nil, &queryError{tok.pos + opAt, len(op), "project only supports : and !="}
+		}
+		q = projectQuery{value}
+	case "tag":
+		if ordered {
+			return nil, &queryError{tok.pos + opAt, len(op), "tag only supports : and !="}
+		}
+		q = tagQuery{strings.TrimPrefix(value, "+")}
+	case "context":
+		if ordered {
+			return nil, &queryError{tok.pos + opAt, len(op), "context only supports : and !="}
+		}
+		q = contextQuery{strings.TrimPrefix(value, "@")}
+	case "id":
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fail(fmt.Sprintf("invalid id %q", value))
+		}
+		q = idQuery{op, id}
+	default:
+		return nil, &queryError{tok.pos, opAt, fmt.Sprintf("unknown field %q", field)}
+	}
+
+	if negate {
+		return notQuery{q}, nil
+	}
+	return q, nil
+}
+
+// resolveQueryDate turns a date in a query into a local midnight. It accepts
+// the same expressions as due dates (see parseDate), such as today, friday,
+// eom or YYYY-MM-DD.
+func resolveQueryDate(value string, now time.Time) (time.Time, error) {
+	t, err := parseDate(value, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()), nil
+}
+
+// compare applies an ordering operator to the result of a three-way comparison.
+func compare(op string, c int) bool {
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return c == 0
+}
+
+type matchAll struct{}
+
+func (matchAll) matches(Task) bool { return true }
+
+type andQuery struct{ left, right taskQuery }
+
+func (q andQuery) matches(t Task) bool { return q.left.matches(t) && q.right.matches(t) }
+
+type orQuery struct{ left, right taskQuery }
+
+func (q orQuery) matches(t Task) bool { return q.left.matches(t) || q.right.matches(t) }
+
+type notQuery struct{ inner taskQuery }
+
+func (q notQuery) matches(t Task) bool { return !q.inner.matches(t) }
+
+type textQuery struct{ text string }
+
+func (q textQuery) matches(t Task) bool {
+	return strings.Contains(strings.ToLower(t.Description), q.text)
+}
+
+type tagQuery struct{ tag string }
+
+func (q tagQuery) matches(t Task) bool { return t.hasTag(q.tag) }
+
+type contextQuery struct{ context string }
+
+func (q contextQuery) matches(t Task) bool {
+	for _, c := range t.Contexts {
+		if c == q.context {
+			return true
+		}
+	}
+	return false
+}
+
+type projectQuery struct{ project string }
+
+// matches also accepts subprojects, so project:work matches work.infra.
+func (q projectQuery) matches(t Task) bool {
+	return t.Project == q.project || strings.HasPrefix(t.Project, q.project+".")
+}
+
+type statusQuery struct{ status string }
+
+func (q statusQuery) matches(t Task) bool { return t.Status == q.status }
+
+// openQuery matches every task that is not done, whatever its column.
+type openQuery struct{}
+
+func (openQuery) matches(t Task) bool { return !t.isDone() }
+
+type dueSetQuery struct{ set bool }
+
+func (q dueSetQuery) matches(t Task) bool { return (t.Due != nil) == q.set }
+
+type dueQuery struct {
+	op  string
+	day time.Time
+}
+
+// matches compares by calendar day, so due:friday matches any time on Friday.
+func (q dueQuery) matches(t Task) bool {
+	if t.Due == nil {
+		return false
+	}
+	d := time.Date(t.Due.Year(), t.Due.Month(), t.Due.Day(), 0, 0, 0, 0, q.day.Location())
+	return compare(q.op, d.Compare(q.day))
+}
+
+// priorityQuery compares urgency, so priority>M matches H.
+type priorityQuery struct {
+	op   string
+	rank int
+}
+
+func (q priorityQuery) matches(t Task) bool {
+	return compare(q.op, q.rank-priorityRank(t.Priority))
+}
+
+type idQuery struct {
+	op string
+	id int
+}
+
+func (q idQuery) matches(t Task) bool { return compare(q.op, t.ID-q.id) }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenizeQuery(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []string
+	}{
+		{"milk", []string{"milk"}},
+		{"  +work  due<fri ", []string{"+work", "due<fri"}},
+		{"(a or b)", []string{"(", "a", "or", "b", ")"}},
+		{`"buy milk" bread`, []string{"buy milk", "bread"}},
+		// Continuation bytes such as 0x85 and 0xA0 are not spaces.
+		{"voilà café", []string{"voilà", "café"}},
+		{"Ω…x", []string{"Ω…x"}},
+		// Non-ASCII spaces still separate words.
+		{"a b c", []string{"a", "b", "c"}},
+	} {
+		tokens, err := tokenizeQuery(tc.in)
+		if err != nil {
+			t.Errorf("tokenizeQuery(%q): %v", tc.in, err)
+			continue
+		}
+		var got []string
+		for _, tok := range tokens[:len(tokens)-1] {
+			got = append(got, tok.text)
+		}
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("tokenizeQuery(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.Local)
+	day := func(d int) *time.Time {
+		t := time.Date(2026, 10, d, 0, 0, 0, 0, time.Local)
+		return &t
+	}
+	tasks := []Task{
+		{ID: 1, Description: "Buy milk", Status: statusTodo, Tags: []string{"errands"}, Due: day(14), Priority: "H"},
+		{ID: 2, Description: "Write report", Status: statusDoing, Project: "work.reports", Due: day(16), Priority: "M"},
+		{ID: 3, Description: "Voilà le café", Status: statusDone, Tags: []string{"fun"}, Contexts: []string{"home"}},
+		{ID: 4, Description: "Plan trip", Status: statusBacklog, Due: day(20)},
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  string
+	}{
+		{"", "1,2,3,4"},
+		{"milk", "1"},
+		{"MILK", "1"},
+		{`"write report"`, "2"},
+		{"voilà", "3"},
+		{"café", "3"},
+		{"+errands", "1"},
+		{"tag:fun", "3"},
+		{"@home", "3"},
+		{"context:home", "3"},
+		{"project:work", "2"},
+		{"project:work.reports", "2"},
+		{"project:wor", ""},
+		{"status:open", "1,2,4"},
+		{"status:doing", "2"},
+		{"status:wip", "2"},
+		{"status!=done", "1,2,4"},
+		{"priority:H", "1"},
+		{"pri>=M", "1,2"},
+		{"priority:none", "3,4"},
+		{"due:today", "1"},
+		{"due<=fri", "1,2"},
+		{"due>fri", "4"},
+		{"due!=today", "2,4"},
+		{"due:none", "3"},
+		{"due:any", "1,2,4"},
+		{"due<eom", "1,2,4"},
+		{"id>2", "3,4"},
+		{"milk or report", "1,2"},
+		{"status:open and not +errands", "2,4"},
+		{"not (milk or report)", "3,4"},
+		{"(plan or milk) due>today", "4"},
+	} {
+		q, err := parseQuery(tc.query, now)
+		if err != nil {
+			t.Errorf("parseQuery(%q): %v", tc.query, err)
+			continue
+		}
+		var ids []string
+		for _, task := range tasks {
+			if q.matches(task) {
+				ids = append(ids, strconv.Itoa(task.ID))
+			}
+		}
+		if got := strings.Join(ids, ","); got != tc.want {
+			t.Errorf("query %q matched %q, want %q", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, tc := range []struct {
+		query  string
+		pos    int
+		marker string
+	}{
+		{`"unclosed`, 0, "^^^^^^^^^"},
+		{"milk )", 5, "     ^"},
+		{"(milk", 0, "^"},
+		{"milk or", 7, "       ^"},
+		{"and milk", 0, "^^^"},
+		{"size:big", 0, "^^^^"},
+		{"due:someday", 4, "    ^^^^^^^"},
+		{"status<done", 6, "      ^"},
+		{"pri:X", 4, "    ^"},
+		{"id:abc", 3, "   ^^^"},
+		// Markers line up under the characters, not the bytes.
+		{"café )", 6, "     ^"},
+	} {
+		_, err := parseQuery(tc.query, time.Now())
+		qerr, ok := err.(*queryError)
+		if !ok {
+			t.Errorf("parseQuery(%q) error = %v, want a queryError", tc.query, err)
+			continue
+		}
+		if qerr.pos != tc.pos {
+			t.Errorf("parseQuery(%q) error at %d, want %d (%v)", tc.query, qerr.pos, tc.pos, err)
+		}
+		lines := strings.Split(qerr.explain(tc.query), "\n")
+		if marker := strings.TrimPrefix(lines[1], "  "); marker != tc.marker {
+			t.Errorf("parseQuery(%q) marker = %q, want %q", tc.query, marker, tc.marker)
+		}
+	}
+}
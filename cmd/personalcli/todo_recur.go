@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurrence describes how a recurring task repeats. Rules are written as
+// "daily", "weekdays", "weekly", "weekly:mon,thu", "monthly:15" or "every:3d".
+type recurrence struct {
+	kind     string
+	weekdays map[time.Weekday]bool
+	day      int
+	interval int
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseWeekday accepts a weekday as "mon" or "monday" in any case.
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(name)
+	if len(name) < 3 {
+		return 0, false
+	}
+	wd, ok := weekdayNames[name[:3]]
+	if !ok || !strings.HasPrefix(strings.ToLower(wd.String()), name) {
+		return 0, false
+	}
+	return wd, true
+}
+
+// parseRecurrence validates a recurrence rule.
+func parseRecurrence(rule string) (recurrence, error) {
+	kind, arg, _ := strings.Cut(strings.ToLower(strings.TrimSpace(rule)), ":")
+	switch kind {
+	case "daily", "weekdays":
+		if arg == "" {
+			return recurrence{kind: kind}, nil
+		}
+	case "weekly":
+		r := recurrence{kind: kind, weekdays: map[time.Weekday]bool{}}
+		if arg == "" {
+			return r, nil
+		}
+		for _, name := range strings.Split(arg, ",") {
+			wd, ok := parseWeekday(strings.TrimSpace(name))
+			if !ok {
+				return recurrence{}, fmt.Errorf("invalid weekday %q in recurrence %q (use mon, tue, ...)", name, rule)
+			}
+			r.weekdays[wd] = true
+		}
+		return r, nil
+	case "monthly":
+		day, err := strconv.Atoi(arg)
+		if err == nil && day >= 1 && day <= 31 {
+			return recurrence{kind: kind, day: day}, nil
+		}
+		return recurrence{}, fmt.Errorf("invalid recurrence %q (use monthly:N with N from 1 to 31)", rule)
+	case "every":
+		n, err := strconv.Atoi(strings.TrimSuffix(arg, "d"))
+		if err == nil && n > 0 {
+			return recurrence{kind: kind, interval: n}, nil
+		}
+		return recurrence{}, fmt.Errorf("invalid recurrence %q (use every:Nd)", rule)
+	}
+	return recurrence{}, fmt.Errorf("invalid recurrence %q (use daily, weekdays, weekly[:mon,...], monthly:N or every:Nd)", rule)
+}
+
+// next returns the first occurrence strictly after from.
+func (r recurrence) next(from time.Time) time.Time {
+	switch r.kind {
+	case "daily":
+		return from.AddDate(0, 0, 1)
+	case "weekdays":
+		next := from.AddDate(0, 0, 1)
+		for next.Weekday() == time.Saturday || next.Weekday() == time.Sunday {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	case "weekly":
+		if len(r.weekdays) == 0 {
+			return from.AddDate(0, 0, 7)
+		}
+		next := from.AddDate(0, 0, 1)
+		for !r.weekdays[next.Weekday()] {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	case "monthly":
+		// Months shorter than the rule's day fall on their last day.
+		if day := min(r.day, daysIn(from.Year(), from.Month())); from.Day() < day {
+			return time.Date(from.Year(), from.Month(), day, from.Hour(), from.Minute(), 0, 0, from.Location())
+		}
+		first := time.Date(from.Year(), from.Month()+1, 1, from.Hour(), from.Minute(), 0, 0, from.Location())
+		day := min(r.day, daysIn(first.Year(), first.Month()))
+		return first.AddDate(0, 0, day-1)
+	case "every":
+		return from.AddDate(0, 0, r.interval)
+	}
+	return from
+}
+
+// daysIn returns the number of days in the given month.
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nextInstance builds the follow-up task for a completed recurring task.
+// The due date is shifted by the rule until it is no longer in the past, so
+// completing an overdue chore does not create another overdue one.
+func nextInstance(task Task, id int, now time.Time) (Task, error) {
+	r, err := parseRecurrence(task.Recur)
+	if err != nil {
+		return Task{}, err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	from := today
+	if task.Due != nil {
+		from = *task.Due
+	}
+	due := r.next(from)
+	for due.Before(today) {
+		due = r.next(due)
+	}
+
+	next := task
+	next.ID = id
+	next.Status = statusTodo
+	next.Due = &due
+	next.Tags = append([]string(nil), task.Tags...)
+	next.CreatedAt = &now
+	next.CompletedAt = nil
+	next.UUID = ""
+	next.Intervals = nil
+	next.Pomodoros = nil
+	next.Annotations = nil
+	return next, nil
+}
+
+// scheduleNext appends the next instance of the completed task at index if
+// it repeats, returning the grown list and the new task (nil otherwise).
+func scheduleNext(tasks []Task, index int, now time.Time) ([]Task, *Task, error) {
+	if tasks[index].Recur == "" {
+		return tasks, nil, nil
+	}
+	next, err := nextInstance(tasks[index], nextTaskID(tasks), now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(tasks, next), &next, nil
+}
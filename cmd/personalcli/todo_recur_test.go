@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.ParseInLocation(dueDateLayout, s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	for _, tc := range []struct {
+		rule, from, want string
+	}{
+		{"daily", "2026-10-14", "2026-10-15"},
+		{"daily", "2026-12-31", "2027-01-01"},
+		{"weekdays", "2026-10-14", "2026-10-15"},
+		{"weekdays", "2026-10-16", "2026-10-19"},
+		{"weekdays", "2026-10-17", "2026-10-19"},
+		{"weekly", "2026-10-14", "2026-10-21"},
+		{"weekly:fri", "2026-10-14", "2026-10-16"},
+		{"weekly:fri", "2026-10-16", "2026-10-23"},
+		{"weekly:Mon,thu", "2026-10-16", "2026-10-19"},
+		{"weekly:monday,thursday", "2026-10-19", "2026-10-22"},
+		{"monthly:15", "2026-10-14", "2026-10-15"},
+		{"monthly:15", "2026-10-15", "2026-11-15"},
+		// Short months fall on their last day, and the next month goes
+		// back to the rule's day.
+		{"monthly:31", "2026-10-31", "2026-11-30"},
+		{"monthly:31", "2026-11-30", "2026-12-31"},
+		{"monthly:30", "2027-01-30", "2027-02-28"},
+		{"monthly:29", "2028-02-01", "2028-02-29"},
+		{"every:3d", "2026-10-14", "2026-10-17"},
+		{"every:10", "2026-10-25", "2026-11-04"},
+	} {
+		r, err := parseRecurrence(tc.rule)
+		if err != nil {
+			t.Errorf("parseRecurrence(%q): %v", tc.rule, err)
+			continue
+		}
+		if got := r.next(day(tc.from)).Format(dueDateLayout); got != tc.want {
+			t.Errorf("%s after %s = %s, want %s", tc.rule, tc.from, got, tc.want)
+		}
+	}
+}
+
+func TestParseRecurrenceRejects(t *testing.T) {
+	for _, rule := range []string{"", "hourly", "daily:2", "weekly:funday", "weekly:mo", "monthly", "monthly:0",
+		"monthly:32", "every:0d", "every:-2d", "every:xd"} {
+		if _, err := parseRecurrence(rule); err == nil {
+			t.Errorf("parseRecurrence(%q) succeeded, want an error", rule)
+		}
+	}
+}
+
+func TestNextInstance(t *testing.T) {
+	due := func(s string) *time.Time {
+		d, _ := time.ParseInLocation(dueTimeLayout, s, time.UTC)
+		return &d
+	}
+	for _, tc := range []struct {
+		rule string
+		due  *time.Time
+		want string
+	}{
+		{"weekly:fri", due("2026-10-16 09:00"), "2026-10-23 09:00"},
+		// An overdue task moves past today rather than to another
+		// overdue date.
+		{"weekly", due("2026-09-01 00:00"), "2026-10-20 00:00"},
+		{"daily", due("2026-10-01 00:00"), "2026-10-14 00:00"},
+		// Without a due date the rule counts from today.
+		{"every:2d", nil, "2026-10-16 00:00"},
+	} {
+		task := Task{ID: 4, Description: "Water plants", Status: statusDone, Recur: tc.rule, Due: tc.due,
+			Tags: []string{"home"}, UUID: "u", CompletedAt: &testNow,
+			Intervals: []timeInterval{{Start: testNow}}}
+		next, err := nextInstance(task, 9, testNow)
+		if err != nil {
+			t.Fatalf("nextInstance(%q): %v", tc.rule, err)
+		}
+		if got := next.Due.Format(dueTimeLayout); got != tc.want {
+			t.Errorf("next %s instance due %s, want %s", tc.rule, got, tc.want)
+		}
+		if next.ID != 9 || next.Status != statusTodo || next.CompletedAt != nil || next.UUID != "" || next.Intervals != nil {
+			t.Errorf("next instance kept state of the completed task: %+v", next)
+		}
+		if next.Recur != tc.rule || next.Description != task.Description || len(next.Tags) != 1 {
+			t.Errorf("next instance lost the task's details: %+v", next)
+		}
+	}
+}
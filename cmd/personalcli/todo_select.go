@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// taskSelector picks the tasks a bulk command acts on. It is written as a
+// mix of IDs ("3"), ranges ("7-9"), comma lists ("3,5") and query terms
+// such as "+errands" or "due<friday" (see "todo list --help"). With IDs
+// only those tasks are selected, narrowed by the query if one is given;
+// with only a query, every matching task is selected.
+type taskSelector struct {
+	ranges []idRange
+	query  taskQuery
+}
+
+// idRange is an inclusive range of task IDs; a single ID has lo == hi.
+// Ranges are matched against the IDs in use rather than expanded, so a
+// range as wide as "1-99999999" costs nothing.
+type idRange struct {
+	lo, hi int
+}
+
+func (r idRange) String() string {
+	if r.lo == r.hi {
+		return strconv.Itoa(r.lo)
+	}
+	return fmt.Sprintf("%d-%d", r.lo, r.hi)
+}
+
+// parseIDList parses "3", "7-9" or "3,5,7-9" into ID ranges. ok is false
+// when the text is not an ID list at all, so it can be treated as a query
+// term.
+func parseIDList(text string) (ranges []idRange, ok bool, err error) {
+	for _, part := range strings.Split(text, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil || first < 1 {
+			return nil, false, nil
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil {
+				return nil, false, nil
+			}
+			if last < first {
+				return nil, true, fmt.Errorf("invalid range %q (the first ID must not be larger than the last)", part)
+			}
+		}
+		ranges = append(ranges, idRange{first, last})
+	}
+	return ranges, true, nil
+}
+
+// parseSelector builds a selector from command arguments. Relative dates in
+// query terms are resolved against now.
+func parseSelector(args []string, now time.Time) (taskSelector, error) {
+	var sel taskSelector
+	var terms []string
+	for _, arg := range args {
+		ranges, ok, err := parseIDList(arg)
+		if err != nil {
+			return taskSelector{}, err
+		}
+		if !ok {
+			terms = append(terms, arg)
+			continue
+		}
+		sel.ranges = append(sel.ranges, ranges...)
+	}
+	if len(terms) > 0 {
+		query, err := parseQuery(strings.Join(terms, " "), now)
+		if err != nil {
+			return taskSelector{}, err
+		}
+		sel.query = query
+	}
+	if len(sel.ranges) == 0 && sel.query == nil {
+		return taskSelector{}, fmt.Errorf("no tasks selected")
+	}
+	return sel, nil
+}
+
+// selectTasks returns the IDs of the selected tasks, in the order they were
+// asked for, and the single IDs or whole ranges that matched no task.
+func (s taskSelector) selectTasks(tasks []Task) (ids []int, missing []idRange) {
+	if len(s.ranges) == 0 {
+		for _, task := range tasks {
+			if s.query.matches(task) {
+				ids = append(ids, task.ID)
+			}
+		}
+		return ids, nil
+	}
+
+	byID := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		byID = append(byID, task.ID)
+	}
+	sort.Ints(byID)
+	seen := map[int]bool{}
+	for _, r := range s.ranges {
+		i := sort.SearchInts(byID, r.lo)
+		if i == len(byID) || byID[i] > r.hi {
+			missing = append(missing, r)
+			continue
+		}
+		for ; i < len(byID) && byID[i] <= r.hi; i++ {
+			id := byID[i]
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if s.query == nil || s.query.matches(tasks[findTask(tasks, id)]) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, missing
+}
+
+// deepestFirst orders IDs so subtasks come before their parents, letting a
+// batch close a parent together with the subtasks selected alongside it.
+func deepestFirst(tasks []Task, ids []int) {
+	depth := map[int]int{}
+	for _, id := range ids {
+		seen := map[int]bool{}
+		for i := findTask(tasks, id); i != -1 && tasks[i].Parent != 0 && !seen[tasks[i].ID]; i = findTask(tasks, tasks[i].Parent) {
+			seen[tasks[i].ID] = true
+			depth[id]++
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return depth[ids[i]] > depth[ids[j]] })
+}
+
+// reportSelectorError prints a selector problem, marking the offending
+// query term the same way "todo list" does.
+func reportSelectorError(err error, args []string) {
+	fmt.Println("Error in selection:", err)
+	qerr, ok := err.(*queryError)
+	if !ok {
+		return
+	}
+	var terms []string
+	for _, arg := range args {
+		if _, isID, _ := parseIDList(arg); !isID {
+			terms = append(terms, arg)
+		}
+	}
+	fmt.Println(qerr.explain(strings.Join(terms, " ")))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseIDList(t *testing.T) {
+	for _, tc := range []struct {
+		in     string
+		want   string
+		isList bool
+		err    bool
+	}{
+		{in: "3", want: "3", isList: true},
+		{in: "7-9", want: "7-9", isList: true},
+		{in: "3,5,7-9", want: "3 5 7-9", isList: true},
+		{in: "1-99999999", want: "1-99999999", isList: true},
+		{in: "9-7", isList: true, err: true},
+		{in: "0"},
+		{in: "+work"},
+		{in: "3,x"},
+		{in: "3-"},
+		{in: "-3"},
+	} {
+		ranges, isList, err := parseIDList(tc.in)
+		if isList != tc.isList || (err != nil) != tc.err {
+			t.Errorf("parseIDList(%q) = ok %t, err %v; want ok %t, error %t", tc.in, isList, err, tc.isList, tc.err)
+			continue
+		}
+		var got []string
+		for _, r := range ranges {
+			got = append(got, r.String())
+		}
+		if strings.Join(got, " ") != tc.want {
+			t.Errorf("parseIDList(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSelectTasks(t *testing.T) {
+	tasks := []Task{
+		{ID: 2, Description: "Buy milk", Status: statusTodo, Tags: []string{"errands"}},
+		{ID: 5, Description: "Write report", Status: statusTodo},
+		{ID: 7, Description: "Post letters", Status: statusTodo, Tags: []string{"errands"}},
+		{ID: 1000000, Description: "Far away", Status: statusTodo},
+	}
+	for _, tc := range []struct {
+		args    []string
+		ids     string
+		missing string
+	}{
+		{[]string{"5,2"}, "5 2", ""},
+		{[]string{"1-99999999"}, "2 5 7 1000000", ""},
+		{[]string{"3-4", "6", "7"}, "7", "3-4 6"},
+		{[]string{"2", "2-5"}, "2 5", ""},
+		{[]string{"1-10", "+errands"}, "2 7", ""},
+		{[]string{"+errands"}, "2 7", ""},
+	} {
+		sel, err := parseSelector(tc.args, testNow)
+		if err != nil {
+			t.Errorf("parseSelector(%q): %v", tc.args, err)
+			continue
+		}
+		ids, missing := sel.selectTasks(tasks)
+		var gotMissing []string
+		for _, r := range missing {
+			gotMissing = append(gotMissing, r.String())
+		}
+		if got := strings.Trim(fmt.Sprint(ids), "[]"); got != tc.ids {
+			t.Errorf("selecting %q gave %s, want %s", tc.args, got, tc.ids)
+		}
+		if got := strings.Join(gotMissing, " "); got != tc.missing {
+			t.Errorf("selecting %q missed %q, want %q", tc.args, got, tc.missing)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// statsBarWidth is the length of the longest bar in the stats chart.
+const statsBarWidth = 20
+
+// statsBucket counts what happened in one day or week.
+type statsBucket struct {
+	start              time.Time
+	created, completed int
+}
+
+// tagStats summarizes the tasks carrying one tag.
+type tagStats struct {
+	created, completed int
+	// timeToComplete sums over the timed completed tasks, those that
+	// also have a creation time.
+	timeToComplete time.Duration
+	timed          int
+}
+
+// startOfDay returns local midnight on the day of t.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns midnight on the Monday of t's week.
+func startOfWeek(t time.Time) time.Time {
+	day := startOfDay(t)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// completionBuckets counts tasks created and completed in each day (or
+// week) from since up to now.
+func completionBuckets(tasks []Task, since, now time.Time, weekly bool) []statsBucket {
+	start, step := startOfDay, 1
+	if weekly {
+		start, step = startOfWeek, 7
+	}
+	var buckets []statsBucket
+	index := map[time.Time]int{}
+	for day := start(since); !day.After(now); day = day.AddDate(0, 0, step) {
+		index[day] = len(buckets)
+		buckets = append(buckets, statsBucket{start: day})
+	}
+	for _, task := range tasks {
+		if task.CreatedAt != nil {
+			if i, ok := index[start(task.CreatedAt.In(now.Location()))]; ok {
+				buckets[i].created++
+			}
+		}
+		if task.isDone() && task.CompletedAt != nil {
+			if i, ok := index[start(task.CompletedAt.In(now.Location()))]; ok {
+				buckets[i].completed++
+			}
+		}
+	}
+	return buckets
+}
+
+// completionStreaks returns the number of consecutive days, up to today, on
+// which at least one task was completed, and the longest such run ever. A
+// streak still counts as current until a whole day passes without a
+// completion, so it does not reset first thing in the morning.
+func completionStreaks(tasks []Task, now time.Time) (current, longest int) {
+	days := map[time.Time]bool{}
+	for _, task := range tasks {
+		if task.isDone() && task.CompletedAt != nil {
+			days[startOfDay(task.CompletedAt.In(now.Location()))] = true
+		}
+	}
+
+	sorted := make([]time.Time, 0, len(days))
+	for day := range days {
+		sorted = append(sorted, day)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+	run := 0
+	for i, day := range sorted {
+		if i > 0 && sorted[i-1].AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+
+	day := startOfDay(now)
+	if !days[day] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for days[day] {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+	return current, longest
+}
+
+// formatSpan renders a longer duration compactly, e.g. "3d04h" or "2h05m".
+func formatSpan(d time.Duration) string {
+	if d >= 24*time.Hour {
+		d = d.Round(time.Hour)
+		return fmt.Sprintf("%dd%02dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+	return formatDuration(d)
+}
+
+// statsBar draws a horizontal bar for n out of maxN.
+func statsBar(n, maxN int, fill string) string {
+	if maxN == 0 || n == 0 {
+		return ""
+	}
+	return strings.Repeat(fill, max(1, n*statsBarWidth/maxN))
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show tasks created vs completed, time to complete, streaks and tags",
+	Long: `Show statistics for your retros: tasks created and completed per day or week as
+a bar chart, the average time from creating a task to completing it, the
+current and longest run of days with at least one completion, and a
+breakdown per tag. Archived tasks are included.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		by, _ := cmd.Flags().GetString("by")
+		if by != "day" && by != "week" {
+			fmt.Printf("Error: invalid grouping %q (use day or week)\n", by)
+			os.Exit(1)
+		}
+		weekly := by == "week"
+
+		now := currentTime()
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		if sinceFlag == "" {
+			sinceFlag = "13d"
+			if weekly {
+				sinceFlag = "55d"
+			}
+		}
+		since, err := parseSince(sinceFlag, now)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		// Start at the first chart bucket so every figure covers the same period.
+		if weekly {
+			since = startOfWeek(since)
+		} else {
+			since = startOfDay(since)
+		}
+
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+		archive, err := readArchive()
+		if err != nil {
+			fmt.Println("Error reading archive:", err)
+			os.Exit(1)
+		}
+		tasks = append(tasks, archive...)
+
+		buckets := completionBuckets(tasks, since, now, weekly)
+		maxCount := 0
+		totalCreated, totalCompleted := 0, 0
+		for _, b := range buckets {
+			maxCount = max(maxCount, b.created, b.completed)
+			totalCreated += b.created
+			totalCompleted += b.completed
+		}
+		label := "Mon Jan 02"
+		if weekly {
+			label = "wk of Jan 02"
+		}
+		fmt.Printf("Created (░) vs completed (█) since %s:\n", since.Format("Mon 2006-01-02"))
+		for _, b := range buckets {
+			fmt.Printf("  %-12s %-*s %3d  %-*s %3d\n", b.start.Format(label),
+				statsBarWidth, statsBar(b.created, maxCount, "░"), b.created,
+				statsBarWidth, statsBar(b.completed, maxCount, "█"), b.completed)
+		}
+		fmt.Printf("  %-12s %*s %3d  %*s %3d\n", "total", statsBarWidth, "", totalCreated, statsBarWidth, "", totalCompleted)
+
+		// Time to complete and the tag breakdown cover the same period.
+		var spent time.Duration
+		var timed int
+		byTag := map[string]*tagStats{}
+		for _, task := range tasks {
+			createdIn := task.CreatedAt != nil && !task.CreatedAt.Before(since)
+			completedIn := task.isDone() && task.CompletedAt != nil && !task.CompletedAt.Before(since)
+			if !createdIn && !completedIn {
+				continue
+			}
+			tags := task.Tags
+			if len(tags) == 0 {
+				tags = []string{""}
+			}
+			for _, tag := range tags {
+				s := byTag[tag]
+				if s == nil {
+					s = &tagStats{}
+					byTag[tag] = s
+				}
+				if createdIn {
+					s.created++
+				}
+				if completedIn {
+					s.completed++
+					if task.CreatedAt != nil {
+						s.timeToComplete += task.CompletedAt.Sub(*task.CreatedAt)
+						s.timed++
+					}
+				}
+			}
+			if completedIn && task.CreatedAt != nil {
+				spent += task.CompletedAt.Sub(*task.CreatedAt)
+				timed++
+			}
+		}
+
+		fmt.Println()
+		if timed > 0 {
+			fmt.Printf("Average time to complete: %s (%d task(s))\n", formatSpan(spent/time.Duration(timed)), timed)
+		} else {
+			fmt.Println("Average time to complete: no completed tasks with a creation time")
+		}
+		current, longest := completionStreaks(tasks, now)
+		fmt.Printf("Completion streak: %d day(s) current, %d day(s) longest\n", current, longest)
+
+		if len(byTag) == 0 {
+			return
+		}
+		tags := make([]string, 0, len(byTag))
+		for tag := range byTag {
+			tags = append(tags, tag)
+		}
+		sort.Slice(tags, func(i, j int) bool {
+			a, b := byTag[tags[i]], byTag[tags[j]]
+			if a.completed != b.completed {
+				return a.completed > b.completed
+			}
+			return tags[i] < tags[j]
+		})
+		fmt.Println()
+		fmt.Println("Per tag:")
+		fmt.Printf("  %-16s %7s %9s %8s\n", "tag", "created", "completed", "avg")
+		for _, tag := range tags {
+			s := byTag[tag]
+			name := "+" + tag
+			if tag == "" {
+				name = "(untagged)"
+			}
+			avg := "-"
+			if s.timed > 0 {
+				avg = formatSpan(s.timeToComplete / time.Duration(s.timed))
+			}
+			fmt.Printf("  %-16s %7d %9d %8s\n", name, s.created, s.completed, avg)
+		}
+	},
+}
+
+func init() {
+	todoCmd.AddCommand(statsCmd)
+	statsCmd.Flags().String("by", "day", "Chart per day or week")
+	statsCmd.Flags().String("since", "", "Start of the period: today, a weekday, Nd or YYYY-MM-DD (default: 2 weeks, or 8 weeks --by week)")
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// remoteTask is a task as stored by a sync service, reduced to the fields
+// that are synced: the description, completion and the due date.
+type remoteTask struct {
+	ID        string
+	Title     string
+	Done      bool
+	Completed *time.Time
+	Due       *time.Time
+	Updated   time.Time
+	// Version changes whenever the remote item does, e.g. its ETag.
+	Version string
+}
+
+// syncBackend is a remote task collection that "todo sync" can reconcile
+// the local list with.
+type syncBackend interface {
+	list() ([]remoteTask, error)
+	create(task remoteTask) (remoteTask, error)
+	update(task remoteTask) (remoteTask, error)
+	remove(id string) error
+}
+
+// syncLink pairs a local task with its remote copy as of the last sync.
+type syncLink struct {
+	UUID          string `json:"uuid"`
+	RemoteID      string `json:"remote_id"`
+	LocalHash     string `json:"local_hash"`
+	RemoteVersion string `json:"remote_version"`
+}
+
+// syncState is what a backend remembers between syncs of one todo list.
+type syncState struct {
+	Target   string     `json:"target"`
+	User     string     `json:"user,omitempty"`
+	LastSync *time.Time `json:"last_sync,omitempty"`
+	Links    []syncLink `json:"links"`
+}
+
+// syncReport counts what a sync changed on each side.
+type syncReport struct {
+	pushed, pulled              int
+	createdRemote, createdLocal int
+	deletedRemote, deletedLocal int
+	conflicts                   int
+	// skipped describes the tasks left alone because the backend refused
+	// to change them; they are retried on the next sync.
+	skipped []string
+}
+
+func (r syncReport) String() string {
+	s := fmt.Sprintf("%d sent, %d received, %d created remotely, %d created locally, %d deleted remotely, %d deleted locally, %d conflict(s) resolved",
+		r.pushed, r.pulled, r.createdRemote, r.createdLocal, r.deletedRemote, r.deletedLocal, r.conflicts)
+	if len(r.skipped) > 0 {
+		s += fmt.Sprintf(", %d skipped", len(r.skipped))
+	}
+	return s
+}
+
+// changed reports whether the sync changed anything on either side.
+func (r syncReport) changed() bool {
+	return r.pushed+r.pulled+r.createdRemote+r.createdLocal+r.deletedRemote+r.deletedLocal > 0
+}
+
+// syncItemError is a backend failure that concerns a single task, such as
+// a write refused because the task changed remotely since it was listed.
+// The sync skips that task instead of stopping.
+type syncItemError struct {
+	err error
+}
+
+func (e syncItemError) Error() string { return e.err.Error() }
+
+// syncStateFilePath returns the state file for a backend, next to the
+// tasks file of the current list.
+func syncStateFilePath(backend string) string {
+	return strings.TrimSuffix(tasksFilePath, ".json") + ".sync-" + backend + ".json"
+}
+
+// readSyncState reads the sync state for a backend.
+func readSyncState(backend string) (syncState, error) {
+	var state syncState
+	data, err := os.ReadFile(syncStateFilePath(backend))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// writeSyncState writes the sync state for a backend.
+func writeSyncState(backend string, state syncState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(syncStateFilePath(backend), data, 0644)
+}
+
+// syncHash fingerprints the synced fields of a local task, so later syncs
+// can tell whether it changed. Due dates are compared by day because not
+// every service stores a time of day.
+func syncHash(task Task) string {
+	due := ""
+	if task.Due != nil {
+		due = task.Due.Format(dueDateLayout)
+	}
+	return fmt.Sprintf("%s|%t|%s", task.Description, task.isDone(), due)
+}
+
+// toRemote converts a local task for sending to a backend.
+func toRemote(task Task, id string) remoteTask {
+	return remoteTask{
+		ID:        id,
+		Title:     task.Description,
+		Done:      task.isDone(),
+		Completed: task.CompletedAt,
+		Due:       task.Due,
+	}
+}
+
+// applyRemote copies the synced fields of a remote task onto a local one.
+func applyRemote(task *Task, r remoteTask, now time.Time) {
+	task.Description = r.Title
+	switch {
+	case r.Done && !task.isDone():
+		completed := now
+		if r.Completed != nil {
+			completed = *r.Completed
+		}
+		task.complete(completed)
+	case !r.Done && task.isDone():
+		task.reopen()
+	}
+	switch {
+	case r.Due == nil:
+		task.Due = nil
+	case task.Due == nil || task.Due.Format(dueDateLayout) != r.Due.Format(dueDateLayout):
+		// Keep the local time of day when only the other side dropped it.
+		due := time.Date(r.Due.Year(), r.Due.Month(), r.Due.Day(), 0, 0, 0, 0, time.Local)
+		task.Due = &due
+	}
+}
+
+// syncTasks reconciles tasks with a backend in both directions. Tasks
+// changed on one side since the last sync are copied to the other; when
+// both sides changed, the most recently modified version wins. Deleting a
+// task on one side deletes it on the other, unless the other side changed it
+// in the meantime. It returns the updated local tasks.
+//
+// Remote changes cannot be rolled back, so when a backend call fails the
+// sync stops but still returns the tasks and links as far as it got; the
+// links it did not reach are kept as they were.
+func syncTasks(tasks []Task, state *syncState, backend syncBackend, now time.Time) ([]Task, syncReport, error) {
+	var report syncReport
+
+	remotes, err := backend.list()
+	if err != nil {
+		return nil, report, err
+	}
+	remoteByID := map[string]remoteTask{}
+	for _, r := range remotes {
+		remoteByID[r.ID] = r
+	}
+
+	for i := range tasks {
+		if tasks[i].UUID == "" {
+			tasks[i].UUID = uuid.NewString()
+		}
+	}
+	localByUUID := map[string]int{}
+	for i, task := range tasks {
+		localByUUID[task.UUID] = i
+	}
+
+	var links []syncLink
+	linked := map[string]bool{}
+	remove := map[int]bool{}
+	stop := func(next int, err error) ([]Task, syncReport, error) {
+		state.Links = append(links, state.Links[next:]...)
+		if len(remove) > 0 {
+			tasks = removeTasks(tasks, remove)
+		}
+		return tasks, report, err
+	}
+	for k, link := range state.Links {
+		i, haveLocal := localByUUID[link.UUID]
+		r, haveRemote := remoteByID[link.RemoteID]
+		delete(remoteByID, link.RemoteID)
+		linked[link.UUID] = true
+
+		switch {
+		case haveLocal && haveRemote:
+			localChanged := syncHash(tasks[i]) != link.LocalHash
+			remoteChanged := r.Version != link.RemoteVersion
+			push, pull := localChanged, remoteChanged
+			if localChanged && remoteChanged {
+				report.conflicts++
+				push = tasks[i].lastModified().After(r.Updated)
+				pull = !push
+			}
+			if push {
+				updated, err := backend.update(toRemote(tasks[i], r.ID))
+				if _, ok := err.(syncItemError); ok {
+					report.skipped = append(report.skipped, fmt.Sprintf("updating task %d: %v", tasks[i].ID, err))
+					links = append(links, link)
+					continue
+				}
+				if err != nil {
+					return stop(k, fmt.Errorf("updating task %d: %v", tasks[i].ID, err))
+				}
+				link.RemoteVersion = updated.Version
+				report.pushed++
+			}
+			if pull {
+				applyRemote(&tasks[i], r, now)
+				link.RemoteVersion = r.Version
+				report.pulled++
+			}
+			link.LocalHash = syncHash(tasks[i])
+			links = append(links, link)
+
+		case haveLocal:
+			// Deleted remotely: follow, unless it was edited here since.
+			if syncHash(tasks[i]) == link.LocalHash {
+				remove[tasks[i].ID] = true
+				report.deletedLocal++
+			} else {
+				linked[link.UUID] = false
+			}
+
+		case haveRemote:
+			// Deleted here: follow, unless it was edited remotely since.
+			if r.Version == link.RemoteVersion {
+				err := backend.remove(r.ID)
+				if _, ok := err.(syncItemError); ok {
+					report.skipped = append(report.skipped, fmt.Sprintf("deleting %q: %v", r.Title, err))
+					links = append(links, link)
+					continue
+				}
+				if err != nil {
+					return stop(k, fmt.Errorf("deleting %q: %v", r.Title, err))
+				}
+				report.deletedRemote++
+			} else {
+				remoteByID[r.ID] = r
+			}
+		}
+	}
+
+	for i := range tasks {
+		if linked[tasks[i].UUID] {
+			continue
+		}
+		created, err := backend.create(toRemote(tasks[i], ""))
+		if _, ok := err.(syncItemError); ok {
+			report.skipped = append(report.skipped, fmt.Sprintf("creating task %d: %v", tasks[i].ID, err))
+			continue
+		}
+		if err != nil {
+			return stop(len(state.Links), fmt.Errorf("creating task %d: %v", tasks[i].ID, err))
+		}
+		links = append(links, syncLink{tasks[i].UUID, created.ID, syncHash(tasks[i]), created.Version})
+		report.createdRemote++
+	}
+
+	// Remote tasks nobody has seen yet become local tasks, in remote order.
+	for _, r := range remotes {
+		if _, ok := remoteByID[r.ID]; !ok {
+			continue
+		}
+		task := Task{
+			ID:        nextTaskID(tasks),
+			Status:    statusTodo,
+			CreatedAt: &now,
+			UUID:      uuid.NewString(),
+		}
+		applyRemote(&task, r, now)
+		tasks = append(tasks, task)
+		links = append(links, syncLink{task.UUID, r.ID, syncHash(task), r.Version})
+		report.createdLocal++
+	}
+
+	if len(remove) > 0 {
+		tasks = removeTasks(tasks, remove)
+	}
+	state.Links = links
+	state.LastSync = &now
+	return tasks, report, nil
+}
+
+// runSync syncs the current list with a backend and saves both the tasks and
+// the sync state.
+func runSync(name string, state syncState, backend syncBackend) error {
+	tasks, err := readTasks()
+	if err != nil {
+		return fmt.Errorf("reading tasks: %v", err)
+	}
+	tasks, report, syncErr := syncTasks(tasks, &state, backend, time.Now())
+	if syncErr != nil && !report.changed() {
+		return syncErr
+	}
+	// Save what a failed sync did get done, or the next sync would repeat
+	// it and duplicate tasks. Without the tasks on disk the saved links
+	// would read as local deletions next time, so only record the state
+	// once they are written.
+	if err := saveTasks("sync "+name, tasks); err != nil {
+		return fmt.Errorf("writing tasks: %v", err)
+	}
+	if err := writeSyncState(name, state); err != nil {
+		return fmt.Errorf("writing sync state: %v", err)
+	}
+	for _, skipped := range report.skipped {
+		fmt.Println("Skipped", skipped)
+	}
+	if syncErr != nil {
+		fmt.Printf("Partly synced with %s: %s.\n", name, report)
+		return syncErr
+	}
+	fmt.Printf("Synced with %s: %s.\n", name, report)
+	return nil
+}
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Two-way sync your todo list with another service",
+}
+
+func init() {
+	todoCmd.AddCommand(syncCmd)
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// caldavPasswordEnvVar holds the CalDAV password so it stays out of shell
+// history and process listings.
+const caldavPasswordEnvVar = "PERSONALCLI_CALDAV_PASSWORD"
+
+// iCalendar date and date-time layouts.
+const (
+	icalDateLayout     = "20060102"
+	icalDateTimeLayout = "20060102T150405"
+	icalUTCLayout      = "20060102T150405Z"
+)
+
+// caldavItem is a VTODO resource as last seen on the server.
+type caldavItem struct {
+	etag string
+	data string
+}
+
+// caldavBackend syncs with a VTODO collection on a CalDAV server such as
+// Nextcloud or Radicale. Remote tasks are identified by their resource
+// path and their ETags tell when they changed. Updates only replace the
+// synced properties, so anything else a client stored on the VTODO is kept.
+type caldavBackend struct {
+	collection *url.URL
+	user       string
+	password   string
+	client     *http.Client
+	items      map[string]caldavItem
+}
+
+// caldavMultistatus is the subset of a WebDAV multistatus response we read.
+type caldavMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				ETag         string `xml:"getetag"`
+				CalendarData string `xml:"calendar-data"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+const caldavQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO"/></c:comp-filter></c:filter>
+</c:calendar-query>`
+
+const caldavETagQuery = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:getetag/></d:prop></d:propfind>`
+
+// caldavStatusError is a response with a status code the request did not
+// expect, such as 412 when a task changed since it was listed.
+type caldavStatusError struct {
+	method, path, status string
+}
+
+func (e caldavStatusError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.method, e.path, e.status)
+}
+
+// itemError turns a refused write into a syncItemError, so the sync skips
+// that task; failing to reach the server still stops it.
+func itemError(err error) error {
+	if _, ok := err.(caldavStatusError); ok {
+		return syncItemError{err}
+	}
+	return err
+}
+
+// do sends a request to path (relative to the collection) and checks that
+// the server answered with one of the expected status codes.
+func (b *caldavBackend) do(method, path, body string, header map[string]string, expect ...int) (*http.Response, []byte, error) {
+	target, err := b.collection.Parse(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest(method, target.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	if b.user != "" {
+		req.SetBasicAuth(b.user, b.password)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, code := range expect {
+		if resp.StatusCode == code {
+			return resp, data, nil
+		}
+	}
+	return nil, nil, caldavStatusError{method, target.Path, resp.Status}
+}
+
+func (b *caldavBackend) list() ([]remoteTask, error) {
+	_, data, err := b.do("REPORT", "", caldavQuery, map[string]string{
+		"Depth":        "1",
+		"Content-Type": "application/xml; charset=utf-8",
+	}, http.StatusMultiStatus)
+	if err != nil {
+		return nil, err
+	}
+	var ms caldavMultistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("reading CalDAV response: %v", err)
+	}
+
+	b.items = map[string]caldavItem{}
+	var out []remoteTask
+	for _, resp := range ms.Responses {
+		for _, ps := range resp.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") || ps.Prop.CalendarData == "" {
+				continue
+			}
+			item := caldavItem{etag: ps.Prop.ETag, data: ps.Prop.CalendarData}
+			b.items[resp.Href] = item
+			out = append(out, parseVTODO(resp.Href, item))
+		}
+	}
+	return out, nil
+}
+
+// etag looks up the current ETag of a resource, for servers that do not
+// return one from PUT.
+func (b *caldavBackend) etag(href string) (string, error) {
+	_, data, err := b.do("PROPFIND", href, caldavETagQuery, map[string]string{
+		"Depth":        "0",
+		"Content-Type": "application/xml; charset=utf-8",
+	}, http.StatusMultiStatus)
+	if err != nil {
+		return "", err
+	}
+	var ms caldavMultistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return "", fmt.Errorf("reading CalDAV response: %v", err)
+	}
+	for _, resp := range ms.Responses {
+		for _, ps := range resp.Propstat {
+			if ps.Prop.ETag != "" {
+				return ps.Prop.ETag, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no ETag for %s", href)
+}
+
+// put stores a VTODO and returns it as the server now has it.
+func (b *caldavBackend) put(href, data string, header map[string]string, now time.Time) (remoteTask, error) {
+	header["Content-Type"] = "text/calendar; charset=utf-8"
+	resp, _, err := b.do("PUT", href, data, header, http.StatusCreated, http.StatusNoContent, http.StatusOK)
+	if err != nil {
+		return remoteTask{}, itemError(err)
+	}
+	// The write went through, so an unknown ETag must not fail it: an empty
+	// version just makes the next sync read the task back.
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		etag, _ = b.etag(href)
+	}
+	item := caldavItem{etag: etag, data: data}
+	b.items[href] = item
+	r := parseVTODO(href, item)
+	r.Updated = now
+	return r, nil
+}
+
+func (b *caldavBackend) create(r remoteTask) (remoteTask, error) {
+	uid := uuid.NewString()
+	href := b.collection.JoinPath(uid + ".ics").Path
+	now := time.Now()
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//personalcli//todo//EN",
+		"BEGIN:VTODO",
+		"UID:" + uid,
+		"DTSTAMP:" + now.UTC().Format(icalUTCLayout),
+		"CREATED:" + now.UTC().Format(icalUTCLayout),
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n") + "\r\n"
+	data = setVTODOFields(data, r, now)
+	return b.put(href, data, map[string]string{"If-None-Match": "*"}, now)
+}
+
+func (b *caldavBackend) update(r remoteTask) (remoteTask, error) {
+	item, ok := b.items[r.ID]
+	if !ok {
+		return remoteTask{}, fmt.Errorf("unknown CalDAV resource %s", r.ID)
+	}
+	now := time.Now()
+	// If-Match makes the server refuse the write if the task changed since
+	// it was listed, rather than silently dropping that change.
+	return b.put(r.ID, setVTODOFields(item.data, r, now), map[string]string{"If-Match": item.etag}, now)
+}
+
+func (b *caldavBackend) remove(id string) error {
+	header := map[string]string{}
+	if item, ok := b.items[id]; ok {
+		header["If-Match"] = item.etag
+	}
+	_, _, err := b.do("DELETE", id, "", header, http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+	return itemError(err)
+}
+
+// unfoldICal splits iCalendar text into logical lines, joining folded ones.
+func unfoldICal(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// foldICal writes logical lines as iCalendar text, folding long lines.
+func foldICal(lines []string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		for len(line) > 75 {
+			cut := 75
+			for cut > 1 && !isRuneStart(line[cut]) {
+				cut--
+			}
+			b.WriteString(line[:cut] + "\r\n ")
+			line = line[cut:]
+		}
+		b.WriteString(line + "\r\n")
+	}
+	return b.String()
+}
+
+func isRuneStart(c byte) bool { return c&0xC0 != 0x80 }
+
+// splitICalLine splits "NAME;PARAM=X:value" into its name, parameters and
+// value.
+func splitICalLine(line string) (name string, params []string, value string) {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	return strings.ToUpper(parts[0]), parts[1:], value
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+var icalUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// parseICalTime reads a DATE or DATE-TIME value. Times without a zone are
+// taken in their TZID, or local time if the zone is unknown.
+func parseICalTime(params []string, value string) (*time.Time, error) {
+	loc := time.Local
+	for _, p := range params {
+		key, tz, _ := strings.Cut(p, "=")
+		if strings.EqualFold(key, "TZID") {
+			if l, err := time.LoadLocation(strings.Trim(tz, `"`)); err == nil {
+				loc = l
+			}
+		}
+	}
+	// The Z of UTC times is a literal in the layout, so give their zone.
+	if t, err := time.ParseInLocation(icalUTCLayout, value, time.UTC); err == nil {
+		return &t, nil
+	}
+	for _, layout := range []string{icalDateTimeLayout, icalDateLayout} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date %q", value)
+}
+
+// parseVTODO reads the synced fields of the first VTODO in data.
+func parseVTODO(href string, item caldavItem) remoteTask {
+	r := remoteTask{ID: href, Version: item.etag}
+	inTodo := false
+	var stamp time.Time
+	for _, line := range unfoldICal(item.data) {
+		name, params, value := splitICalLine(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VTODO"):
+			inTodo = true
+		case name == "END" && strings.EqualFold(value, "VTODO"):
+			inTodo = false
+		case !inTodo:
+		case name == "SUMMARY":
+			r.Title = icalUnescaper.Replace(value)
+		case name == "STATUS":
+			r.Done = strings.EqualFold(value, "COMPLETED")
+		case name == "COMPLETED":
+			r.Completed, _ = parseICalTime(params, value)
+		case name == "DUE":
+			r.Due, _ = parseICalTime(params, value)
+		case name == "LAST-MODIFIED":
+			if t, err := parseICalTime(params, value); err == nil {
+				r.Updated = *t
+			}
+		case name == "DTSTAMP":
+			if t, err := parseICalTime(params, value); err == nil {
+				stamp = *t
+			}
+		}
+	}
+	if r.Updated.IsZero() {
+		r.Updated = stamp
+	}
+	return r
+}
+
+// vtodoSyncedFields are the properties setVTODOFields rewrites.
+var vtodoSyncedFields = map[string]bool{
+	"SUMMARY": true, "STATUS": true, "COMPLETED": true, "PERCENT-COMPLETE": true,
+	"DUE": true, "LAST-MODIFIED": true, "DTSTAMP": true,
+}
+
+// setVTODOFields replaces the synced properties of the VTODO in data with
+// those of r, leaving every other property as it was.
+func setVTODOFields(data string, r remoteTask, now time.Time) string {
+	fields := []string{
+		"SUMMARY:" + icalEscaper.Replace(r.Title),
+		"DTSTAMP:" + now.UTC().Format(icalUTCLayout),
+		"LAST-MODIFIED:" + now.UTC().Format(icalUTCLayout),
+	}
+	if r.Done {
+		completed := now
+		if r.Completed != nil {
+			completed = *r.Completed
+		}
+		fields = append(fields, "STATUS:COMPLETED", "PERCENT-COMPLETE:100", "COMPLETED:"+completed.UTC().Format(icalUTCLayout))
+	} else {
+		fields = append(fields, "STATUS:NEEDS-ACTION")
+	}
+	if r.Due != nil {
+		if r.Due.Hour() == 0 && r.Due.Minute() == 0 {
+			fields = append(fields, "DUE;VALUE=DATE:"+r.Due.Format(icalDateLayout))
+		} else {
+			fields = append(fields, "DUE:"+r.Due.UTC().Format(icalUTCLayout))
+		}
+	}
+
+	var out []string
+	inTodo, done := false, false
+	for _, line := range unfoldICal(data) {
+		name, _, value := splitICalLine(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VTODO"):
+			inTodo = !done
+		case name == "END" && strings.EqualFold(value, "VTODO") && inTodo:
+			out = append(out, fields...)
+			inTodo, done = false, true
+		case inTodo && vtodoSyncedFields[name]:
+			continue
+		}
+		out = append(out, line)
+	}
+	return foldICal(out)
+}
+
+var syncCaldavCmd = &cobra.Command{
+	Use:   "caldav",
+	Short: "Two-way sync the todo list with a CalDAV task collection",
+	Long: `Two-way sync the todo list with a VTODO collection on a CalDAV server such as
+Nextcloud or Radicale. Descriptions, completion and due dates are synced;
+other fields stay local, and properties other clients set on the server are
+kept. ETags tell which tasks changed; when a task was changed on both sides,
+the most recent change wins.
+
+The first sync needs the collection URL, e.g.
+
+  personalcli todo sync caldav --url https://cloud.example.com/remote.php/dav/calendars/me/tasks/ --user me
+
+and later syncs remember the URL and user. The password is read from
+$PERSONALCLI_CALDAV_PASSWORD.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		state, err := readSyncState("caldav")
+		if err != nil {
+			fmt.Println("Error reading sync state:", err)
+			os.Exit(1)
+		}
+
+		target, user := state.Target, state.User
+		if cmd.Flags().Changed("user") {
+			user, _ = cmd.Flags().GetString("user")
+		}
+		if rawURL, _ := cmd.Flags().GetString("url"); rawURL != "" {
+			if !strings.HasSuffix(rawURL, "/") {
+				rawURL += "/"
+			}
+			target = rawURL
+		}
+		if target == "" {
+			fmt.Println("Error: no CalDAV collection configured yet. Pass --url (and --user).")
+			os.Exit(1)
+		}
+		collection, err := url.Parse(target)
+		if err != nil || collection.Scheme == "" || collection.Host == "" {
+			fmt.Printf("Error: invalid CalDAV URL %q.\n", target)
+			os.Exit(1)
+		}
+		if target != state.Target {
+			// Links made against another collection mean nothing for this one.
+			state = syncState{Target: target}
+		}
+		state.User = user
+
+		backend := &caldavBackend{
+			collection: collection,
+			user:       user,
+			password:   os.Getenv(caldavPasswordEnvVar),
+			client:     &http.Client{Timeout: 30 * time.Second},
+		}
+		if err := runSync("caldav", state, backend); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	syncCmd.AddCommand(syncCaldavCmd)
+	syncCaldavCmd.Flags().String("url", "", "URL of the CalDAV task collection")
+	syncCaldavCmd.Flags().String("user", "", "User name for the CalDAV server (password from $"+caldavPasswordEnvVar+")")
+}
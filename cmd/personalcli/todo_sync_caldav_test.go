@@ -0,0 +1,265 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	// Loaded so the TZID test passes without system zone files.
+	_ "time/tzdata"
+)
+
+// fakeCalDAV is an in-memory stand-in for a CalDAV task collection. It
+// honors If-Match and If-None-Match like a real server and, like some
+// servers, only returns ETags from PUT when etagOnPut is set.
+type fakeCalDAV struct {
+	mu        sync.Mutex
+	items     map[string]string
+	etagOnPut bool
+}
+
+func caldavTestETag(data string) string {
+	return fmt.Sprintf(`"%x"`, sha1.Sum([]byte(data)))
+}
+
+func (f *fakeCalDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	data, exists := f.items[r.URL.Path]
+
+	switch r.Method {
+	case "REPORT":
+		paths := make([]string, 0, len(f.items))
+		for path := range f.items {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		var b strings.Builder
+		b.WriteString(`<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+		for _, path := range paths {
+			fmt.Fprintf(&b, `<d:response><d:href>%s</d:href><d:propstat><d:prop><d:getetag>%s</d:getetag><c:calendar-data>`,
+				path, caldavTestETag(f.items[path]))
+			xml.EscapeText(&b, []byte(f.items[path]))
+			b.WriteString(`</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+		}
+		b.WriteString(`</d:multistatus>`)
+		w.WriteHeader(http.StatusMultiStatus)
+		io.WriteString(w, b.String())
+	case "PROPFIND":
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprintf(w, `<d:multistatus xmlns:d="DAV:"><d:response><d:href>%s</d:href><d:propstat><d:prop><d:getetag>%s</d:getetag></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response></d:multistatus>`,
+			r.URL.Path, caldavTestETag(data))
+	case http.MethodPut:
+		if r.Header.Get("If-None-Match") == "*" && exists ||
+			r.Header.Get("If-Match") != "" && (!exists || r.Header.Get("If-Match") != caldavTestETag(data)) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		f.items[r.URL.Path] = string(body)
+		if f.etagOnPut {
+			w.Header().Set("ETag", caldavTestETag(string(body)))
+		}
+		if exists {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+	case http.MethodDelete:
+		switch {
+		case !exists:
+			http.NotFound(w, r)
+		case r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != caldavTestETag(data):
+			w.WriteHeader(http.StatusPreconditionFailed)
+		default:
+			delete(f.items, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// vtodo returns a VTODO resource as another client might write it.
+func vtodo(uid, summary string, extra ...string) string {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//other//EN", "BEGIN:VTODO", "UID:" + uid,
+		"DTSTAMP:20261017T080000Z", "SUMMARY:" + summary}
+	lines = append(lines, extra...)
+	lines = append(lines, "END:VTODO", "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// newFakeCalDAV starts a fake CalDAV server and a backend for its
+// /tasks/ collection.
+func newFakeCalDAV(t *testing.T, etagOnPut bool) (*fakeCalDAV, *caldavBackend) {
+	t.Helper()
+	fake := &fakeCalDAV{items: map[string]string{}, etagOnPut: etagOnPut}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	collection, err := url.Parse(server.URL + "/tasks/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, &caldavBackend{collection: collection, client: server.Client()}
+}
+
+func TestCalDAVSync(t *testing.T) {
+	for _, etagOnPut := range []bool{false, true} {
+		t.Run(fmt.Sprintf("etag on put %t", etagOnPut), func(t *testing.T) {
+			useTempTasks(t)
+			t.Setenv(nowEnvVar, "2026-10-17T09:00:00Z")
+			now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+			fake, backend := newFakeCalDAV(t, etagOnPut)
+			fake.items["/tasks/phone.ics"] = vtodo("phone", "Call the plumber", "DUE;VALUE=DATE:20261020", "CATEGORIES:home")
+
+			// Create both ways.
+			local := []Task{{ID: 1, Description: "Buy milk, eggs", Status: statusTodo}}
+			var state syncState
+			local, report := syncOnce(t, local, &state, backend, now)
+			if report.createdRemote != 1 || report.createdLocal != 1 {
+				t.Fatalf("report = %+v, want one task created on each side", report)
+			}
+			if got := strings.Join(taskTitles(local), "|"); got != "Buy milk, eggs|Call the plumber" {
+				t.Errorf("local tasks = %s", got)
+			}
+			if due := local[1].Due; due == nil || due.Format(dueDateLayout) != "2026-10-20" {
+				t.Errorf("pulled due date = %v, want 2026-10-20", due)
+			}
+			milk := state.Links[0].RemoteID
+			if !strings.Contains(fake.items[milk], `SUMMARY:Buy milk\, eggs`) {
+				t.Errorf("created VTODO lacks an escaped summary:\n%s", fake.items[milk])
+			}
+
+			// Push a completion, pull a rename; the other client's
+			// properties survive the update.
+			local[0].complete(now)
+			fake.items["/tasks/phone.ics"] = vtodo("phone", "Call the plumber today", "DUE;VALUE=DATE:20261020", "CATEGORIES:home")
+			local, report = syncOnce(t, local, &state, backend, now)
+			if report.pushed != 1 || report.pulled != 1 {
+				t.Errorf("report = %+v, want one push and one pull", report)
+			}
+			if !strings.Contains(fake.items[milk], "STATUS:COMPLETED") {
+				t.Errorf("completion not sent:\n%s", fake.items[milk])
+			}
+			if local[1].Description != "Call the plumber today" {
+				t.Errorf("rename not pulled: %q", local[1].Description)
+			}
+			local[1].Description = "Plumber"
+			local, _ = syncOnce(t, local, &state, backend, now)
+			if phone := fake.items["/tasks/phone.ics"]; !strings.Contains(phone, "CATEGORIES:home") || !strings.Contains(phone, "SUMMARY:Plumber") {
+				t.Errorf("update lost other properties or the new summary:\n%s", phone)
+			}
+
+			// Delete in each direction.
+			delete(fake.items, "/tasks/phone.ics")
+			local, report = syncOnce(t, local, &state, backend, now)
+			if report.deletedLocal != 1 || len(local) != 1 {
+				t.Errorf("remote deletion not followed: %+v, %d local task(s)", report, len(local))
+			}
+			local, report = syncOnce(t, local[:0], &state, backend, now)
+			if report.deletedRemote != 1 || len(fake.items) != 0 {
+				t.Errorf("local deletion not followed: %+v, %d remote item(s)", report, len(fake.items))
+			}
+		})
+	}
+}
+
+func TestCalDAVUpdateRefusesStaleWrites(t *testing.T) {
+	useTempTasks(t)
+	t.Setenv(nowEnvVar, "2026-10-17T09:00:00Z")
+	fake, backend := newFakeCalDAV(t, true)
+	fake.items["/tasks/a.ics"] = vtodo("a", "Original")
+
+	remotes, err := backend.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another client changes the task after it was listed.
+	fake.items["/tasks/a.ics"] = vtodo("a", "Changed elsewhere")
+	r := remotes[0]
+	r.Title = "Changed here"
+	if _, err := backend.update(r); err == nil {
+		t.Fatal("update overwrote a task changed since it was listed")
+	}
+	if !strings.Contains(fake.items["/tasks/a.ics"], "Changed elsewhere") {
+		t.Error("the other client's change was lost")
+	}
+}
+
+func TestParseVTODO(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:x\r\nSUMMARY:A long summary that is folded\r\n  across lines\\; with escapes\r\n" +
+		"STATUS:COMPLETED\r\nCOMPLETED:20261016T120000Z\r\nDUE;TZID=Europe/Paris:20261018T170000\r\n" +
+		"LAST-MODIFIED:20261016T130000Z\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	r := parseVTODO("/tasks/x.ics", caldavItem{etag: `"1"`, data: data})
+
+	if r.ID != "/tasks/x.ics" || r.Version != `"1"` {
+		t.Errorf("ID, Version = %q, %q", r.ID, r.Version)
+	}
+	if want := "A long summary that is folded across lines; with escapes"; r.Title != want {
+		t.Errorf("Title = %q, want %q", r.Title, want)
+	}
+	if !r.Done || r.Completed == nil || !r.Completed.Equal(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Done, Completed = %t, %v", r.Done, r.Completed)
+	}
+	if r.Due == nil || !r.Due.Equal(time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)) {
+		t.Errorf("Due = %v, want 17:00 Paris time", r.Due)
+	}
+	if !r.Updated.Equal(time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC)) {
+		t.Errorf("Updated = %v", r.Updated)
+	}
+}
+
+func TestCalDAVSyncSkipsStaleItems(t *testing.T) {
+	useTempTasks(t)
+	t.Setenv(nowEnvVar, "2026-10-17T09:00:00Z")
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	fake, backend := newFakeCalDAV(t, true)
+
+	local := []Task{
+		{ID: 1, Description: "Stale", Status: statusTodo},
+		{ID: 2, Description: "Fine", Status: statusTodo},
+	}
+	var state syncState
+	local, _ = syncOnce(t, local, &state, backend, now)
+	stale := state.Links[0].RemoteID
+
+	// Both change here; another client edits the first one after it was
+	// listed, so the server refuses that update.
+	local[0].Description = "Stale, edited here"
+	local[1].Description = "Fine, edited here"
+	flaky := &flakyBackend{syncBackend: backend, afterList: func() {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		fake.items[stale] = vtodo("stale", "Edited elsewhere")
+	}}
+	local, report := syncOnce(t, local, &state, flaky, now)
+	if len(report.skipped) != 1 || report.pushed != 1 {
+		t.Errorf("report = %+v, want one item skipped and one sent", report)
+	}
+	if !strings.Contains(fake.items[stale], "Edited elsewhere") {
+		t.Error("the other client's change was overwritten")
+	}
+
+	// The next sync sees both edits and resolves them as a conflict.
+	local[0].ModifiedAt = &now
+	later := now.Add(time.Hour)
+	local, report = syncOnce(t, local, &state, backend, later)
+	if report.conflicts != 1 || len(report.skipped) != 0 {
+		t.Errorf("report = %+v, want one conflict", report)
+	}
+	if len(local) != 2 || len(fake.items) != 2 {
+		t.Errorf("%d local and %d remote task(s), want 2 each", len(local), len(fake.items))
+	}
+}
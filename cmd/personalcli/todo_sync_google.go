@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/tasks/v1"
+)
+
+// googleTasksBackend syncs with one Google Tasks list.
+type googleTasksBackend struct {
+	srv    *tasks.Service
+	listID string
+}
+
+// googleDueLayout is how Google Tasks writes due dates; only the date part
+// is kept by the service.
+const googleDueLayout = "2006-01-02T00:00:00.000Z"
+
+func fromGoogleTask(t *tasks.Task) remoteTask {
+	r := remoteTask{
+		ID:      t.Id,
+		Title:   t.Title,
+		Done:    t.Status == "completed",
+		Version: t.Etag,
+	}
+	if r.Version == "" {
+		r.Version = t.Updated
+	}
+	if updated, err := time.Parse(time.RFC3339, t.Updated); err == nil {
+		r.Updated = updated
+	}
+	if due, err := time.Parse(time.RFC3339, t.Due); err == nil {
+		r.Due = &due
+	}
+	if t.Completed != nil {
+		if completed, err := time.Parse(time.RFC3339, *t.Completed); err == nil {
+			r.Completed = &completed
+		}
+	}
+	return r
+}
+
+func toGoogleTask(r remoteTask) *tasks.Task {
+	t := &tasks.Task{Title: r.Title, Status: "needsAction"}
+	if r.Done {
+		t.Status = "completed"
+		if r.Completed != nil {
+			completed := r.Completed.UTC().Format(time.RFC3339)
+			t.Completed = &completed
+		}
+	} else {
+		t.NullFields = append(t.NullFields, "Completed")
+	}
+	if r.Due != nil {
+		t.Due = r.Due.Format(googleDueLayout)
+	} else {
+		t.NullFields = append(t.NullFields, "Due")
+	}
+	return t
+}
+
+func (b *googleTasksBackend) list() ([]remoteTask, error) {
+	var out []remoteTask
+	call := b.srv.Tasks.List(b.listID).ShowCompleted(true).ShowHidden(true).ShowDeleted(true).MaxResults(100)
+	err := call.Pages(context.Background(), func(page *tasks.Tasks) error {
+		for _, t := range page.Items {
+			if !t.Deleted {
+				out = append(out, fromGoogleTask(t))
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (b *googleTasksBackend) create(r remoteTask) (remoteTask, error) {
+	t, err := b.srv.Tasks.Insert(b.listID, toGoogleTask(r)).Do()
+	if err != nil {
+		return remoteTask{}, err
+	}
+	return fromGoogleTask(t), nil
+}
+
+func (b *googleTasksBackend) update(r remoteTask) (remoteTask, error) {
+	t, err := b.srv.Tasks.Patch(b.listID, r.ID, toGoogleTask(r)).Do()
+	if err != nil {
+		return remoteTask{}, err
+	}
+	return fromGoogleTask(t), nil
+}
+
+func (b *googleTasksBackend) remove(id string) error {
+	err := b.srv.Tasks.Delete(b.listID, id).Do()
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) && gerr.Code == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// findGoogleTaskList returns the ID of the Google Tasks list with the given
+// title, creating the list if there is none.
+func findGoogleTaskList(srv *tasks.Service, title string) (string, error) {
+	var id string
+	err := srv.Tasklists.List().MaxResults(100).Pages(context.Background(), func(page *tasks.TaskLists) error {
+		for _, list := range page.Items {
+			if list.Title == title && id == "" {
+				id = list.Id
+			}
+		}
+		return nil
+	})
+	if err != nil || id != "" {
+		return id, err
+	}
+	list, err := srv.Tasklists.Insert(&tasks.TaskList{Title: title}).Do()
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Created Google Tasks list %q.\n", title)
+	return list.Id, nil
+}
+
+var syncGoogleCmd = &cobra.Command{
+	Use:   "google",
+	Short: "Two-way sync the todo list with a Google Tasks list",
+	Long: `Two-way sync the todo list with a Google Tasks list. Descriptions, completion
+and due dates (by day) are synced; other fields stay local. When a task was
+changed on both sides, the most recent change wins.
+
+The first sync uses --tasklist (default: your default Google Tasks list) and
+later syncs remember it. Uses the same credentials.json as the calendar
+command; if you authorized before Tasks support, delete token.json so the
+Tasks permission is requested.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		state, err := readSyncState("google")
+		if err != nil {
+			fmt.Println("Error reading sync state:", err)
+			os.Exit(1)
+		}
+
+		client, err := getClient()
+		if err != nil {
+			fmt.Printf("Unable to get Google client: %v\n", err)
+			os.Exit(1)
+		}
+		srv, err := tasks.NewService(context.Background(), option.WithHTTPClient(client))
+		if err != nil {
+			fmt.Printf("Unable to create Tasks client: %v\n", err)
+			os.Exit(1)
+		}
+
+		target := state.Target
+		if title, _ := cmd.Flags().GetString("tasklist"); title != "" {
+			if target, err = findGoogleTaskList(srv, title); err != nil {
+				fmt.Println("Error finding Google Tasks list:", err)
+				os.Exit(1)
+			}
+		}
+		if target == "" {
+			target = "@default"
+		}
+		if target != state.Target {
+			// Links made against another list mean nothing for this one.
+			state = syncState{Target: target}
+		}
+
+		err = runSync("google", state, &googleTasksBackend{srv: srv, listID: target})
+		var gerr *googleapi.Error
+		if errors.As(err, &gerr) && gerr.Code == http.StatusForbidden {
+			fmt.Println("Error:", err)
+			fmt.Println("Google refused access to Tasks. If you authorized before Tasks sync existed, delete ~/.config/personalcli/token.json and try again.")
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	syncCmd.AddCommand(syncGoogleCmd)
+	syncGoogleCmd.Flags().String("tasklist", "", "Title of the Google Tasks list to sync with (created if missing)")
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/tasks/v1"
+)
+
+// useTempTasks points the task files at a fresh directory for one test.
+func useTempTasks(t *testing.T) {
+	t.Helper()
+	dir, path := tasksDir, tasksFilePath
+	tasksDir = t.TempDir()
+	tasksFilePath = filepath.Join(tasksDir, "tasks.json")
+	t.Cleanup(func() { tasksDir, tasksFilePath = dir, path })
+}
+
+// fakeGoogleTasks is an in-memory stand-in for the Google Tasks REST API,
+// serving a single task list.
+type fakeGoogleTasks struct {
+	mu    sync.Mutex
+	items map[string]map[string]any
+	order []string
+	next  int
+	// now stamps the updated time of items as they change.
+	now time.Time
+}
+
+func (f *fakeGoogleTasks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Paths look like /tasks/v1/lists/{list}/tasks[/{task}].
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/tasks/v1/lists/"), "/")
+	if len(parts) < 2 || parts[1] != "tasks" {
+		http.NotFound(w, r)
+		return
+	}
+	id := ""
+	if len(parts) > 2 {
+		id = parts[2]
+	}
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		items := []map[string]any{}
+		for _, id := range f.order {
+			items = append(items, f.items[id])
+		}
+		json.NewEncoder(w).Encode(map[string]any{"items": items})
+	case r.Method == http.MethodPost && id == "":
+		var item map[string]any
+		json.NewDecoder(r.Body).Decode(&item)
+		f.next++
+		id = fmt.Sprintf("g%d", f.next)
+		f.items[id] = item
+		f.order = append(f.order, id)
+		f.touch(id)
+		json.NewEncoder(w).Encode(item)
+	case r.Method == http.MethodPatch && f.items[id] != nil:
+		var patch map[string]any
+		json.NewDecoder(r.Body).Decode(&patch)
+		f.patch(id, patch)
+		json.NewEncoder(w).Encode(f.items[id])
+	case r.Method == http.MethodDelete && f.items[id] != nil:
+		f.remove(id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// touch gives an item a new ETag and updated time.
+func (f *fakeGoogleTasks) touch(id string) {
+	f.next++
+	f.items[id]["id"] = id
+	f.items[id]["etag"] = fmt.Sprintf("etag-%d", f.next)
+	f.items[id]["updated"] = f.now.UTC().Format(time.RFC3339)
+}
+
+// patch changes fields of an item as the API does; null removes a field.
+func (f *fakeGoogleTasks) patch(id string, fields map[string]any) {
+	for key, value := range fields {
+		if value == nil {
+			delete(f.items[id], key)
+		} else {
+			f.items[id][key] = value
+		}
+	}
+	f.touch(id)
+}
+
+func (f *fakeGoogleTasks) remove(id string) {
+	delete(f.items, id)
+	for i, other := range f.order {
+		if other == id {
+			f.order = append(f.order[:i], f.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// add stores an item as if created on another device.
+func (f *fakeGoogleTasks) add(fields map[string]any) string {
+	f.next++
+	id := fmt.Sprintf("g%d", f.next)
+	f.items[id] = fields
+	f.order = append(f.order, id)
+	f.touch(id)
+	return id
+}
+
+// titles returns the titles of all items in order.
+func (f *fakeGoogleTasks) titles() []string {
+	var titles []string
+	for _, id := range f.order {
+		titles = append(titles, f.items[id]["title"].(string))
+	}
+	return titles
+}
+
+// newFakeGoogleTasks starts a fake Tasks API and a backend talking to it.
+func newFakeGoogleTasks(t *testing.T, now time.Time) (*fakeGoogleTasks, *googleTasksBackend) {
+	t.Helper()
+	fake := &fakeGoogleTasks{items: map[string]map[string]any{}, now: now}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	srv, err := tasks.NewService(context.Background(),
+		option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, &googleTasksBackend{srv: srv, listID: "@default"}
+}
+
+// taskTitles returns the descriptions of tasks in order.
+func taskTitles(tasks []Task) []string {
+	var titles []string
+	for _, task := range tasks {
+		titles = append(titles, task.Description)
+	}
+	return titles
+}
+
+// syncOnce runs a sync and fails the test on error.
+func syncOnce(t *testing.T, tasks []Task, state *syncState, backend syncBackend, now time.Time) ([]Task, syncReport) {
+	t.Helper()
+	tasks, report, err := syncTasks(tasks, state, backend, now)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	return tasks, report
+}
+
+// flakyBackend wraps a backend to run a hook after listing and to fail the
+// failAt-th write (create, update or remove) as a dropped connection would.
+type flakyBackend struct {
+	syncBackend
+	afterList func()
+	failAt    int
+	writes    int
+}
+
+func (b *flakyBackend) list() ([]remoteTask, error) {
+	remotes, err := b.syncBackend.list()
+	if b.afterList != nil {
+		b.afterList()
+	}
+	return remotes, err
+}
+
+func (b *flakyBackend) write() error {
+	b.writes++
+	if b.writes == b.failAt {
+		return fmt.Errorf("connection reset")
+	}
+	return nil
+}
+
+func (b *flakyBackend) create(r remoteTask) (remoteTask, error) {
+	if err := b.write(); err != nil {
+		return remoteTask{}, err
+	}
+	return b.syncBackend.create(r)
+}
+
+func (b *flakyBackend) update(r remoteTask) (remoteTask, error) {
+	if err := b.write(); err != nil {
+		return remoteTask{}, err
+	}
+	return b.syncBackend.update(r)
+}
+
+func (b *flakyBackend) remove(id string) error {
+	if err := b.write(); err != nil {
+		return err
+	}
+	return b.syncBackend.remove(id)
+}
+
+func TestGoogleSyncCreatesBothWays(t *testing.T) {
+	useTempTasks(t)
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	fake, backend := newFakeGoogleTasks(t, now)
+	fake.add(map[string]any{"title": "Call the plumber", "status": "needsAction", "due": "2026-10-20T00:00:00.000Z"})
+
+	local := []Task{{ID: 1, Description: "Buy milk", Status: statusTodo}}
+	var state syncState
+	local, report := syncOnce(t, local, &state, backend, now)
+
+	if report.createdRemote != 1 || report.createdLocal != 1 {
+		t.Errorf("report = %+v, want one task created on each side", report)
+	}
+	if got := strings.Join(taskTitles(local), ","); got != "Buy milk,Call the plumber" {
+		t.Errorf("local tasks = %s", got)
+	}
+	if got := strings.Join(fake.titles(), ","); got != "Call the plumber,Buy milk" {
+		t.Errorf("remote tasks = %s", got)
+	}
+	if due := local[1].Due; due == nil || due.Format(dueDateLayout) != "2026-10-20" {
+		t.Errorf("pulled due date = %v, want 2026-10-20", due)
+	}
+	if local[1].ID != 2 {
+		t.Errorf("pulled task ID = %d, want 2", local[1].ID)
+	}
+
+	// A second sync with nothing changed does nothing.
+	_, report = syncOnce(t, local, &state, backend, now)
+	if report.changed() || report.conflicts != 0 {
+		t.Errorf("idle sync report = %+v, want nothing", report)
+	}
+}
+
+func TestGoogleSyncPushesAndPulls(t *testing.T) {
+	useTempTasks(t)
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	fake, backend := newFakeGoogleTasks(t, now)
+
+	local := []Task{
+		{ID: 1, Description: "Buy milk", Status: statusTodo},
+		{ID: 2, Description: "Water plants", Status: statusTodo},
+	}
+	var state syncState
+	local, _ = syncOnce(t, local, &state, backend, now)
+	milk, plants := state.Links[0].RemoteID, state.Links[1].RemoteID
+
+	// Finish one task here and rename the other on the phone.
+	later := now.Add(time.Hour)
+	local[0].complete(later)
+	fake.now = later
+	fake.patch(plants, map[string]any{"title": "Water all plants"})
+
+	local, report := syncOnce(t, local, &state, backend, later)
+	if report.pushed != 1 || report.pulled != 1 || report.conflicts != 0 {
+		t.Errorf("report = %+v, want one push and one pull", report)
+	}
+	if status := fake.items[milk]["status"]; status != "completed" {
+		t.Errorf("remote status = %v, want completed", status)
+	}
+	if local[1].Description != "Water all plants" {
+		t.Errorf("local description = %q, want the remote rename", local[1].Description)
+	}
+
+	// Reopening on the phone reopens it here.
+	fake.patch(milk, map[string]any{"status": "needsAction", "completed": nil})
+	local, _ = syncOnce(t, local, &state, backend, later)
+	if local[0].isDone() {
+		t.Error("task still done after being reopened remotely")
+	}
+}
+
+func TestGoogleSyncConflictLastModifiedWins(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		localAfter  time.Duration
+		remoteAfter time.Duration
+		want        string
+	}{
+		{"local newer", 2 * time.Hour, time.Hour, "Local title"},
+		{"remote newer", time.Hour, 2 * time.Hour, "Remote title"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			useTempTasks(t)
+			now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+			fake, backend := newFakeGoogleTasks(t, now)
+
+			local := []Task{{ID: 1, Description: "Original", Status: statusTodo}}
+			var state syncState
+			local, _ = syncOnce(t, local, &state, backend, now)
+			remoteID := state.Links[0].RemoteID
+
+			localTime := now.Add(tc.localAfter)
+			local[0].Description = "Local title"
+			local[0].ModifiedAt = &localTime
+			fake.now = now.Add(tc.remoteAfter)
+			fake.patch(remoteID, map[string]any{"title": "Remote title"})
+
+			local, report := syncOnce(t, local, &state, backend, now.Add(3*time.Hour))
+			if report.conflicts != 1 {
+				t.Errorf("conflicts = %d, want 1", report.conflicts)
+			}
+			if local[0].Description != tc.want {
+				t.Errorf("local description = %q, want %q", local[0].Description, tc.want)
+			}
+			if title := fake.items[remoteID]["title"]; title != tc.want {
+				t.Errorf("remote title = %q, want %q", title, tc.want)
+			}
+
+			// Both sides agree now, so the next sync is idle.
+			if _, report = syncOnce(t, local, &state, backend, now.Add(4*time.Hour)); report.changed() || report.conflicts != 0 {
+				t.Errorf("sync after conflict = %+v, want nothing", report)
+			}
+		})
+	}
+}
+
+func TestGoogleSyncDeletes(t *testing.T) {
+	useTempTasks(t)
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	fake, backend := newFakeGoogleTasks(t, now)
+
+	local := []Task{
+		{ID: 1, Description: "Deleted here", Status: statusTodo},
+		{ID: 2, Description: "Deleted there", Status: statusTodo},
+		{ID: 3, Description: "Deleted there, edited here", Status: statusTodo},
+		{ID: 4, Description: "Kept", Status: statusTodo},
+	}
+	var state syncState
+	local, _ = syncOnce(t, local, &state, backend, now)
+
+	local = local[1:]
+	fake.remove(state.Links[1].RemoteID)
+	fake.remove(state.Links[2].RemoteID)
+	local[1].Description = "Edited here"
+
+	local, report := syncOnce(t, local, &state, backend, now.Add(time.Hour))
+	if report.deletedRemote != 1 || report.deletedLocal != 1 {
+		t.Errorf("report = %+v, want one deletion on each side", report)
+	}
+	if got := strings.Join(taskTitles(local), ","); got != "Edited here,Kept" {
+		t.Errorf("local tasks = %s", got)
+	}
+	// The task edited here after it was deleted remotely is sent again.
+	if got := strings.Join(fake.titles(), ","); got != "Kept,Edited here" {
+		t.Errorf("remote tasks = %s", got)
+	}
+}
+
+func TestSyncFailingHalfwayKeepsProgress(t *testing.T) {
+	useTempTasks(t)
+	t.Setenv(nowEnvVar, "2026-10-17T09:00:00Z")
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	fake, backend := newFakeGoogleTasks(t, now)
+
+	// Sync two tasks, then delete one here and add two more.
+	local := []Task{
+		{ID: 1, Description: "Deleted here", Status: statusTodo},
+		{ID: 2, Description: "Kept", Status: statusTodo},
+	}
+	var state syncState
+	local, _ = syncOnce(t, local, &state, backend, now)
+	local = append(local[1:],
+		Task{ID: 3, Description: "New one", Status: statusTodo},
+		Task{ID: 4, Description: "New two", Status: statusTodo})
+	if err := writeTasks(local); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSyncState("google", state); err != nil {
+		t.Fatal(err)
+	}
+
+	// The remote deletion and the first create go through; the second
+	// create fails.
+	if err := runSync("google", state, &flakyBackend{syncBackend: backend, failAt: 3}); err == nil {
+		t.Fatal("sync succeeded, want the injected failure")
+	}
+	if got := strings.Join(fake.titles(), ","); got != "Kept,New one" {
+		t.Fatalf("remote tasks after the failure = %s", got)
+	}
+
+	// The next sync finishes the job without repeating any of it.
+	state, err := readSyncState("google")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runSync("google", state, backend); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(fake.titles(), ","); got != "Kept,New one,New two" {
+		t.Errorf("remote tasks = %s", got)
+	}
+	saved, err := readTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(taskTitles(saved), ","); got != "Kept,New one,New two" {
+		t.Errorf("local tasks = %s", got)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// projectPrefix marks a project token, e.g. "project:infra".
+const projectPrefix = "project:"
+
+// parseTaskTokens splits command-line words into a plain description plus
+// any "+tag" and "project:name" tokens found among them.
+func parseTaskTokens(args []string) (description string, tags []string, project string) {
+	var words []string
+	for _, word := range strings.Fields(strings.Join(args, " ")) {
+		switch {
+		case len(word) > 1 && strings.HasPrefix(word, "+"):
+			tags = addTag(tags, word[1:])
+		case len(word) > len(projectPrefix) && strings.HasPrefix(word, projectPrefix):
+			project = word[len(projectPrefix):]
+		default:
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " "), tags, project
+}
+
+// addTag appends tag to tags unless it is already present.
+func addTag(tags []string, tag string) []string {
+	for _, t := range tags {
+		if t == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+// removeTag returns tags without the given tag.
+func removeTag(tags []string, tag string) []string {
+	var kept []string
+	for _, t := range tags {
+		if t != tag {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// hasTag reports whether the task carries the given tag.
+func (t Task) hasTag(tag string) bool {
+	for _, tt := range t.Tags {
+		if tt == tag {
+			return true
+		}
+	}
+	return false
+}
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "Summarize task counts per tag and project",
+	Run: func(cmd *cobra.Command, args []string) {
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+
+		tagCounts := map[string]int{}
+		projectCounts := map[string]int{}
+		for _, task := range tasks {
+			for _, tag := range task.Tags {
+				tagCounts[tag]++
+			}
+			if task.Project != "" {
+				projectCounts[task.Project]++
+			}
+		}
+
+		if len(tagCounts) == 0 && len(projectCounts) == 0 {
+			fmt.Println("No tags or projects in use. Add some with 'personalcli todo add \"my task +tag project:name\"'")
+			return
+		}
+
+		if len(tagCounts) > 0 {
+			fmt.Println("Tags:")
+			for _, tag := range sortedKeys(tagCounts) {
+				fmt.Printf("  +%s: %d\n", tag, tagCounts[tag])
+			}
+		}
+		if len(projectCounts) > 0 {
+			fmt.Println("Projects:")
+			for _, project := range sortedKeys(projectCounts) {
+				fmt.Printf("  %s: %d\n", project, projectCounts[project])
+			}
+		}
+	},
+}
+
+// sortedKeys returns the keys of a count map in alphabetical order.
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	todoCmd.AddCommand(tagsCmd)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// taskwarriorTimeLayout is the compact UTC timestamp format used by Taskwarrior.
+const taskwarriorTimeLayout = "20060102T150405Z"
+
+// taskAnnotation is a timestamped note attached to a task.
+type taskAnnotation struct {
+	Entry       time.Time `json:"entry"`
+	Description string    `json:"description"`
+}
+
+// taskwarriorTask mirrors one object in the output of "task export".
+type taskwarriorTask struct {
+	ID          int                     `json:"id"`
+	UUID        string                  `json:"uuid"`
+	Description string                  `json:"description"`
+	Status      string                  `json:"status"`
+	Entry       string                  `json:"entry,omitempty"`
+	End         string                  `json:"end,omitempty"`
+	Due         string                  `json:"due,omitempty"`
+	Project     string                  `json:"project,omitempty"`
+	Priority    string                  `json:"priority,omitempty"`
+	Tags        []string                `json:"tags,omitempty"`
+	Annotations []taskwarriorAnnotation `json:"annotations,omitempty"`
+	Depends     json.RawMessage         `json:"depends,omitempty"`
+}
+
+type taskwarriorAnnotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// taskUUID returns the task's UUID, deriving a stable one from its ID and
+// creation time for tasks that were never given one.
+func taskUUID(task Task) string {
+	if task.UUID != "" {
+		return task.UUID
+	}
+	name := fmt.Sprintf("personalcli-task-%d", task.ID)
+	if task.CreatedAt != nil {
+		name += "-" + task.CreatedAt.UTC().Format(taskwarriorTimeLayout)
+	}
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)).String()
+}
+
+func formatTaskwarriorTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(taskwarriorTimeLayout)
+}
+
+func parseTaskwarriorTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(taskwarriorTimeLayout, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Taskwarrior date %q", value)
+	}
+	t = t.Local()
+	return &t, nil
+}
+
+// parseTaskwarriorDepends accepts both the array form used by Taskwarrior
+// 2.6+ and the older comma-separated string.
+func parseTaskwarriorDepends(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+	var joined string
+	if err := json.Unmarshal(raw, &joined); err != nil {
+		return nil, fmt.Errorf("invalid depends value %s", raw)
+	}
+	if joined == "" {
+		return nil, nil
+	}
+	return strings.Split(joined, ","), nil
+}
+
+// writeTaskwarrior writes tasks as a JSON array that "task import" accepts.
+// Subtask, recurrence and time-tracking data have no Taskwarrior equivalent
+// and are not written.
+func writeTaskwarrior(w io.Writer, tasks []Task) error {
+	uuids := map[int]string{}
+	for _, task := range tasks {
+		uuids[task.ID] = taskUUID(task)
+	}
+
+	out := make([]taskwarriorTask, 0, len(tasks))
+	for _, task := range tasks {
+		tw := taskwarriorTask{
+			UUID:        uuids[task.ID],
+			Description: task.Description,
+			Status:      "pending",
+			Entry:       formatTaskwarriorTime(task.CreatedAt),
+			Due:         formatTaskwarriorTime(task.Due),
+			Project:     task.Project,
+			Priority:    task.Priority,
+			Tags:        task.Tags,
+		}
+		if task.isDone() {
+			tw.Status = "completed"
+			tw.End = formatTaskwarriorTime(task.CompletedAt)
+		} else {
+			tw.ID = task.ID
+		}
+		if tw.Entry == "" {
+			tw.Entry = currentTime().UTC().Format(taskwarriorTimeLayout)
+		}
+		for _, a := range task.Annotations {
+			tw.Annotations = append(tw.Annotations, taskwarriorAnnotation{
+				Entry:       a.Entry.UTC().Format(taskwarriorTimeLayout),
+				Description: a.Description,
+			})
+		}
+		var depends []string
+		for _, id := range task.BlockedBy {
+			if u, ok := uuids[id]; ok {
+				depends = append(depends, u)
+			}
+		}
+		if len(depends) > 0 {
+			tw.Depends, _ = json.Marshal(depends)
+		}
+		out = append(out, tw)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// mergeTaskwarrior applies the output of "task export" to tasks. Tasks whose
+// UUID is already known are updated in place; the rest are appended with new
+// IDs. Deleted tasks and recurrence templates are skipped. It returns the
+// updated list and the number of tasks added and updated.
+func mergeTaskwarrior(r io.Reader, tasks []Task) ([]Task, int, int, error) {
+	var incoming []taskwarriorTask
+	if err := json.NewDecoder(r).Decode(&incoming); err != nil {
+		return nil, 0, 0, fmt.Errorf("decoding Taskwarrior JSON: %v", err)
+	}
+
+	byUUID := map[string]int{}
+	for i, task := range tasks {
+		byUUID[taskUUID(task)] = i
+	}
+
+	added, updated := 0, 0
+	depends := map[string][]string{}
+	for _, tw := range incoming {
+		if tw.Status == "deleted" || tw.Status == "recurring" {
+			continue
+		}
+		if tw.UUID == "" {
+			return nil, 0, 0, fmt.Errorf("task %q has no uuid", tw.Description)
+		}
+
+		task := Task{
+			UUID:        tw.UUID,
+			Description: tw.Description,
+			Status:      statusTodo,
+			Project:     tw.Project,
+			Tags:        tw.Tags,
+		}
+		switch tw.Status {
+		case "completed":
+			task.Status = statusDone
+		case "waiting":
+			task.Status = statusBlocked
+		}
+		var err error
+		if task.Priority, err = parsePriority(tw.Priority); err != nil {
+			return nil, 0, 0, err
+		}
+		if task.CreatedAt, err = parseTaskwarriorTime(tw.Entry); err != nil {
+			return nil, 0, 0, err
+		}
+		if task.CompletedAt, err = parseTaskwarriorTime(tw.End); err != nil {
+			return nil, 0, 0, err
+		}
+		if task.Due, err = parseTaskwarriorTime(tw.Due); err != nil {
+			return nil, 0, 0, err
+		}
+		for _, a := range tw.Annotations {
+			entry, err := parseTaskwarriorTime(a.Entry)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			annotation := taskAnnotation{Description: a.Description}
+			if entry != nil {
+				annotation.Entry = *entry
+			}
+			task.Annotations = append(task.Annotations, annotation)
+		}
+		if depends[tw.UUID], err = parseTaskwarriorDepends(tw.Depends); err != nil {
+			return nil, 0, 0, err
+		}
+
+		if i, ok := byUUID[tw.UUID]; ok {
+			// Keep the fields Taskwarrior does not know about.
+			existing := tasks[i]
+			task.ID = existing.ID
+			task.Parent = existing.Parent
+			task.Recur = existing.Recur
+			task.Intervals = existing.Intervals
+			task.Pomodoros = existing.Pomodoros
+			task.Contexts = existing.Contexts
+			task.Extras = existing.Extras
+			tasks[i] = task
+			updated++
+		} else {
+			task.ID = nextTaskID(tasks)
+			byUUID[tw.UUID] = len(tasks)
+			tasks = append(tasks, task)
+			added++
+		}
+	}
+
+	// Resolve dependencies once every task has an ID.
+	for u, deps := range depends {
+		task := &tasks[byUUID[u]]
+		task.BlockedBy = nil
+		for _, dep := range deps {
+			if i, ok := byUUID[strings.TrimSpace(dep)]; ok {
+				task.BlockedBy = append(task.BlockedBy, tasks[i].ID)
+			}
+		}
+	}
+	return tasks, added, updated, nil
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// timeInterval is a stretch of tracked work on a task. End is nil while the
+// timer is running.
+type timeInterval struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// activeTask returns the index of the task with a running timer, or -1.
+func activeTask(tasks []Task) int {
+	for i, task := range tasks {
+		if n := len(task.Intervals); n > 0 && task.Intervals[n-1].End == nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// runningTimer finds the task with a running timer in any todo list, since
+// only one timer runs at a time across all of them. It returns the list, its
+// tasks and the task's index, or an index of -1 when no timer is running.
+func runningTimer() (string, []Task, int, error) {
+	names, err := todoLists()
+	if err != nil {
+		return "", nil, -1, err
+	}
+	current := currentList
+	defer useList(current)
+	for _, name := range names {
+		useList(name)
+		tasks, err := readTasks()
+		if err != nil {
+			return "", nil, -1, fmt.Errorf("reading list %s: %v", name, err)
+		}
+		if i := activeTask(tasks); i != -1 {
+			return name, tasks, i, nil
+		}
+	}
+	return "", nil, -1, nil
+}
+
+// inList names a list other than the current one in messages, e.g.
+// " (list work)".
+func inList(name string) string {
+	if name == currentList {
+		return ""
+	}
+	return fmt.Sprintf(" (list %s)", name)
+}
+
+// stopTimer closes the task's running interval, if any, and reports whether
+// one was running.
+func stopTimer(task *Task, now time.Time) bool {
+	n := len(task.Intervals)
+	if n == 0 || task.Intervals[n-1].End != nil {
+		return false
+	}
+	task.Intervals[n-1].End = &now
+	return true
+}
+
+// trackedSince totals the time spent on a task from since until now,
+// clipping intervals that started earlier and counting a running timer up to now.
+func trackedSince(task Task, since, now time.Time) time.Duration {
+	var total time.Duration
+	for _, iv := range task.Intervals {
+		end := now
+		if iv.End != nil {
+			end = *iv.End
+		}
+		start := iv.Start
+		if start.Before(since) {
+			start = since
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
+
+// formatDuration renders a duration as hours and minutes, e.g. "2h05m".
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// parseSince resolves the start of a period looked back on, such as
+// "today", "monday" (the most recent Monday, including today), "7d",
+// "last week" or "2026-10-01". It takes the same dates as "todo add --due".
+func parseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && strings.HasSuffix(value, "d") && days >= 0 {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		return today.AddDate(0, 0, -days), nil
+	}
+	if value == "" {
+		value = "today"
+	}
+	return parsePastDate(value, now)
+}
+
+var startCmd = &cobra.Command{
+	Use:   "start [task_id]",
+	Short: "Start tracking time on a task",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		taskID, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Println("Invalid task ID. Please provide a number.")
+			os.Exit(1)
+		}
+
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+
+		taskIndex := findTask(tasks, taskID)
+		if taskIndex == -1 {
+			fmt.Println("Task ID not found.")
+			os.Exit(1)
+		}
+		list, running, active, err := runningTimer()
+		if err != nil {
+			fmt.Println("Error finding the running timer:", err)
+			os.Exit(1)
+		}
+		if list == currentList && active == taskIndex {
+			fmt.Printf("Already tracking task %d.\n", taskID)
+			return
+		}
+
+		// Only one timer runs at a time, so starting a task stops the
+		// previous one, whichever list it is in.
+		now := time.Now()
+		switch {
+		case active == -1:
+		case list == currentList:
+			stopTimer(&tasks[active], now)
+			fmt.Printf("Stopped task %d: %s\n", tasks[active].ID, tasks[active].Description)
+		default:
+			current := currentList
+			useList(list)
+			stopTimer(&running[active], now)
+			err := saveTasks(fmt.Sprintf("stop %d", running[active].ID), running)
+			useList(current)
+			if err != nil {
+				fmt.Println("Error writing tasks:", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Stopped task %d%s: %s\n", running[active].ID, inList(list), running[active].Description)
+		}
+		tasks[taskIndex].Intervals = append(tasks[taskIndex].Intervals, timeInterval{Start: now})
+
+		if err := saveTasks(fmt.Sprintf("start %d", taskID), tasks); err != nil {
+			fmt.Println("Error writing tasks:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Started task %d: %s\n", taskID, tasks[taskIndex].Description)
+	},
+}
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running timer",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		list, tasks, active, err := runningTimer()
+		if err != nil {
+			fmt.Println("Error finding the running timer:", err)
+			os.Exit(1)
+		}
+		if active == -1 {
+			fmt.Println("No timer is running.")
+			return
+		}
+		label := inList(list)
+		useList(list)
+		task := &tasks[active]
+		start := task.Intervals[len(task.Intervals)-1].Start
+		now := time.Now()
+		stopTimer(task, now)
+
+		if err := saveTasks(fmt.Sprintf("stop %d", task.ID), tasks); err != nil {
+			fmt.Println("Error writing tasks:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Stopped task %d%s after %s.\n", task.ID, label, formatDuration(now.Sub(start)))
+	},
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the running timer",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		list, tasks, active, err := runningTimer()
+		if err != nil {
+			fmt.Println("Error finding the running timer:", err)
+			os.Exit(1)
+		}
+		if active == -1 {
+			fmt.Println("No timer is running.")
+			return
+		}
+		task := tasks[active]
+		start := task.Intervals[len(task.Intervals)-1].Start
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		fmt.Printf("Tracking task %d%s: %s\n", task.ID, inList(list), task.Description)
+		fmt.Printf("Running for %s (started %s), %s today.\n",
+			formatDuration(now.Sub(start)), start.Format("15:04"), formatDuration(trackedSince(task, today, now)))
+	},
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Total tracked time per task, tag or project",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		by, _ := cmd.Flags().GetString("by")
+		if by != "task" && by != "tag" && by != "project" {
+			fmt.Printf("Error: invalid grouping %q (use task, tag or project)\n", by)
+			os.Exit(1)
+		}
+
+		now := currentTime()
+		since, err := parseSince(sinceFlag, now)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+		// Archived tasks keep their intervals, so their time still counts.
+		archive, err := readArchive()
+		if err != nil {
+			fmt.Println("Error reading archive:", err)
+			os.Exit(1)
+		}
+		tasks = append(tasks, archive...)
+
+		totals := map[string]time.Duration{}
+		var grand time.Duration
+		for _, task := range tasks {
+			spent := trackedSince(task, since, now)
+			if spent == 0 {
+				continue
+			}
+			grand += spent
+			switch by {
+			case "task":
+				totals[fmt.Sprintf("%d: %s", task.ID, task.Description)] += spent
+			case "tag":
+				if len(task.Tags) == 0 {
+					totals["(untagged)"] += spent
+				}
+				for _, tag := range task.Tags {
+					totals["+"+tag] += spent
+				}
+			case "project":
+				if task.Project == "" {
+					totals["(no project)"] += spent
+				} else {
+					totals[task.Project] += spent
+				}
+			}
+		}
+
+		fmt.Printf("Tracked time since %s:\n", since.Format("Mon 2006-01-02"))
+		if grand == 0 {
+			fmt.Println("No time tracked.")
+			return
+		}
+
+		keys := make([]string, 0, len(totals))
+		for k := range totals {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if totals[keys[i]] != totals[keys[j]] {
+				return totals[keys[i]] > totals[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		for _, k := range keys {
+			fmt.Printf("  %8s  %s\n", formatDuration(totals[k]), k)
+		}
+		fmt.Printf("  %8s  total\n", formatDuration(grand))
+	},
+}
+
+func init() {
+	todoCmd.AddCommand(startCmd)
+	todoCmd.AddCommand(stopCmd)
+	todoCmd.AddCommand(statusCmd)
+	todoCmd.AddCommand(reportCmd)
+	reportCmd.Flags().String("since", "today", "Start of the report: today, a weekday, Nd or YYYY-MM-DD")
+	reportCmd.Flags().String("by", "task", "Group totals by task, tag or project")
+}
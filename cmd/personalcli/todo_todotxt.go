@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// todo.txt priorities A, B and C map to H, M and L. Lower priorities (D-Z)
+// are treated as L and the original letter is kept in Extras["pri"] so it
+// survives a round trip.
+var todoTxtPriorityRe = regexp.MustCompile(`^\([A-Z]\)$`)
+
+// todoTxtKeyValueRe matches key:value extras. The key must be a word that
+// does not start with a digit, so times such as 10:30 stay in the
+// description, and values starting with "/" are left alone so URLs such as
+// https://example.com do too.
+var todoTxtKeyValueRe = regexp.MustCompile(`^([A-Za-z_][\w-]*):([^\s/][^\s]*)$`)
+
+// priorityFromLetter converts a todo.txt priority letter to a Task priority.
+func priorityFromLetter(letter string) string {
+	switch letter {
+	case "A":
+		return "H"
+	case "B":
+		return "M"
+	case "":
+		return ""
+	}
+	return "L"
+}
+
+// priorityLetter returns the todo.txt priority letter for a task.
+func priorityLetter(task Task) string {
+	if letter := task.Extras["pri"]; letter != "" && priorityFromLetter(letter) == task.Priority {
+		return letter
+	}
+	switch task.Priority {
+	case "H":
+		return "A"
+	case "M":
+		return "B"
+	case "L":
+		return "C"
+	}
+	return ""
+}
+
+// parseTodoTxtDate parses a todo.txt YYYY-MM-DD date.
+func parseTodoTxtDate(value string) (*time.Time, bool) {
+	t, err := time.ParseInLocation(dueDateLayout, value, time.Local)
+	if err != nil {
+		return nil, false
+	}
+	return &t, true
+}
+
+// parseTodoTxtLine converts one todo.txt line into a task without an ID.
+func parseTodoTxtLine(line string) Task {
+	task := Task{Status: statusTodo}
+	fields := strings.Fields(line)
+
+	i := 0
+	letter := ""
+	if len(fields) > 0 && fields[0] == "x" {
+		task.Status = statusDone
+		i++
+		if i < len(fields) {
+			if d, ok := parseTodoTxtDate(fields[i]); ok {
+				task.CompletedAt = d
+				i++
+			}
+		}
+	} else if len(fields) > 0 && todoTxtPriorityRe.MatchString(fields[0]) {
+		letter = fields[0][1:2]
+		i++
+	}
+	if i < len(fields) {
+		if d, ok := parseTodoTxtDate(fields[i]); ok {
+			task.CreatedAt = d
+			i++
+		}
+	}
+
+	parseTodoTxtWords(&task, fields[i:], &letter)
+	// Keep the words as written, so an export of the unchanged task puts
+	// tags, contexts and extras back where they were.
+	task.TodoTxt = strings.Join(fields[i:], " ")
+
+	task.Priority = priorityFromLetter(letter)
+	if task.Priority == "L" && letter != "C" {
+		if task.Extras == nil {
+			task.Extras = map[string]string{}
+		}
+		task.Extras["pri"] = letter
+	}
+	return task
+}
+
+// parseTodoTxtWords sorts the words of a todo.txt line that follow the
+// priority and dates into the task's description, tags, contexts and extras.
+func parseTodoTxtWords(task *Task, fields []string, letter *string) {
+	var words []string
+	for _, field := range fields {
+		switch {
+		case len(field) > 1 && field[0] == '+':
+			task.Tags = addTag(task.Tags, field[1:])
+		case len(field) > 1 && field[0] == '@':
+			task.Contexts = addTag(task.Contexts, field[1:])
+		case todoTxtKeyValueRe.MatchString(field):
+			key, value, _ := strings.Cut(field, ":")
+			if !applyTodoTxtExtra(task, key, value, letter) {
+				if task.Extras == nil {
+					task.Extras = map[string]string{}
+				}
+				task.Extras[key] = value
+			}
+		default:
+			words = append(words, field)
+		}
+	}
+	task.Description = strings.Join(words, " ")
+}
+
+// importedTodoTxt returns the words the task was imported from, if they
+// still describe it: the task has not been edited since in a way that shows
+// in todo.txt. hasLetter reports whether they include a pri: extra.
+func importedTodoTxt(task Task, letter string) (text string, hasLetter bool, ok bool) {
+	if task.TodoTxt == "" {
+		return "", false, false
+	}
+	var parsed Task
+	parsedLetter := ""
+	parseTodoTxtWords(&parsed, strings.Fields(task.TodoTxt), &parsedLetter)
+	extras := maps.Clone(task.Extras)
+	delete(extras, "pri")
+	delete(parsed.Extras, "pri")
+	same := parsed.Description == task.Description &&
+		slices.Equal(parsed.Tags, task.Tags) &&
+		slices.Equal(parsed.Contexts, task.Contexts) &&
+		parsed.Project == task.Project &&
+		parsed.Recur == task.Recur &&
+		sameDay(parsed.Due, task.Due) &&
+		maps.Equal(parsed.Extras, extras) &&
+		(parsedLetter == "" || parsedLetter == letter)
+	return task.TodoTxt, parsedLetter != "", same
+}
+
+// sameDay reports whether two optional dates fall on the same day.
+func sameDay(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Format(dueDateLayout) == b.Format(dueDateLayout)
+}
+
+// applyTodoTxtExtra maps the key:value extras that correspond to Task fields
+// and reports whether it consumed the pair.
+func applyTodoTxtExtra(task *Task, key, value string, letter *string) bool {
+	switch key {
+	case "due":
+		if d, ok := parseTodoTxtDate(value); ok {
+			task.Due = d
+			return true
+		}
+	case "project":
+		task.Project = value
+		return true
+	case "recur":
+		if _, err := parseRecurrence(value); err == nil {
+			task.Recur = value
+			return true
+		}
+	case "pri":
+		if len(value) == 1 && value[0] >= 'A' && value[0] <= 'Z' {
+			*letter = value
+			return true
+		}
+	}
+	return false
+}
+
+// formatTodoTxtLine renders a task as a todo.txt line. Subtask, dependency
+// and time-tracking data have no todo.txt equivalent and are not written.
+func formatTodoTxtLine(task Task) string {
+	var parts []string
+	letter := priorityLetter(task)
+	if task.isDone() {
+		parts = append(parts, "x")
+		if task.CompletedAt != nil {
+			parts = append(parts, task.CompletedAt.Format(dueDateLayout))
+			if task.CreatedAt != nil {
+				parts = append(parts, task.CreatedAt.Format(dueDateLayout))
+			}
+		}
+	} else {
+		if letter != "" {
+			parts = append(parts, "("+letter+")")
+		}
+		if task.CreatedAt != nil {
+			parts = append(parts, task.CreatedAt.Format(dueDateLayout))
+		}
+	}
+
+	if text, hasLetter, ok := importedTodoTxt(task, letter); ok {
+		parts = append(parts, text)
+		if task.isDone() && letter != "" && !hasLetter {
+			parts = append(parts, "pri:"+letter)
+		}
+		return strings.Join(parts, " ")
+	}
+
+	if task.Description != "" {
+		parts = append(parts, task.Description)
+	}
+	for _, tag := range task.Tags {
+		parts = append(parts, "+"+tag)
+	}
+	for _, context := range task.Contexts {
+		parts = append(parts, "@"+context)
+	}
+	if task.Project != "" {
+		parts = append(parts, "project:"+task.Project)
+	}
+	if task.Due != nil {
+		parts = append(parts, "due:"+task.Due.Format(dueDateLayout))
+	}
+	if task.Recur != "" {
+		parts = append(parts, "recur:"+task.Recur)
+	}
+	if task.isDone() && letter != "" {
+		parts = append(parts, "pri:"+letter)
+	}
+
+	keys := make([]string, 0, len(task.Extras))
+	for key := range task.Extras {
+		if key != "pri" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts = append(parts, key+":"+task.Extras[key])
+	}
+	return strings.Join(parts, " ")
+}
+
+// readTodoTxt parses every non-empty line of a todo.txt file.
+func readTodoTxt(r io.Reader) ([]Task, error) {
+	var tasks []Task
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		task := parseTodoTxtLine(line)
+		if task.Description == "" && len(task.Tags) == 0 && len(task.Contexts) == 0 {
+			return nil, fmt.Errorf("line has no description: %q", line)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, scanner.Err()
+}
+
+// writeTodoTxt writes tasks as todo.txt lines.
+func writeTodoTxt(w io.Writer, tasks []Task) error {
+	for _, task := range tasks {
+		if _, err := fmt.Fprintln(w, formatTodoTxtLine(task)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
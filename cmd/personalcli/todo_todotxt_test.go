@@ -0,0 +1,115 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTodoTxtLine(t *testing.T) {
+	for _, tc := range []struct {
+		line        string
+		description string
+		done        bool
+		priority    string
+		created     string
+		completed   string
+		due         string
+		tags        []string
+		contexts    []string
+		project     string
+		extras      map[string]string
+	}{
+		{line: "Buy milk", description: "Buy milk"},
+		{line: "(A) Call mom", description: "Call mom", priority: "H"},
+		{line: "(B) 2026-10-01 Call +family mom @phone", description: "Call mom", priority: "M", created: "2026-10-01",
+			tags: []string{"family"}, contexts: []string{"phone"}},
+		{line: "(E) low", description: "low", priority: "L", extras: map[string]string{"pri": "E"}},
+		{line: "x 2026-10-05 2026-10-01 Pay rent pri:A", description: "Pay rent", done: true, priority: "H",
+			completed: "2026-10-05", created: "2026-10-01"},
+		{line: "x Done without dates", description: "Done without dates", done: true},
+		{line: "Report due:2026-10-20 project:work", description: "Report", due: "2026-10-20", project: "work"},
+		{line: "Ship it custom:val x-id:42", description: "Ship it", extras: map[string]string{"custom": "val", "x-id": "42"}},
+		// Times, URLs and lone colons stay in the description.
+		{line: "Meet at 10:30 with Bob", description: "Meet at 10:30 with Bob"},
+		{line: "Read https://example.com/a:b later", description: "Read https://example.com/a:b later"},
+		{line: "Note: bring 1:2 ratio", description: "Note: bring 1:2 ratio"},
+		// An invalid due date is kept as an extra rather than lost.
+		{line: "Fix due:soon", description: "Fix", extras: map[string]string{"due": "soon"}},
+		// A lowercase x or an x later in the line does not complete a task.
+		{line: "xylophone lesson", description: "xylophone lesson"},
+		{line: "(A) x marks the spot", description: "x marks the spot", priority: "H"},
+	} {
+		task := parseTodoTxtLine(tc.line)
+		if task.Description != tc.description {
+			t.Errorf("%q: description = %q, want %q", tc.line, task.Description, tc.description)
+		}
+		if task.isDone() != tc.done {
+			t.Errorf("%q: done = %t, want %t", tc.line, task.isDone(), tc.done)
+		}
+		if task.Priority != tc.priority {
+			t.Errorf("%q: priority = %q, want %q", tc.line, task.Priority, tc.priority)
+		}
+		for _, date := range []struct {
+			name string
+			got  string
+			want string
+		}{
+			{"created", formatOptionalDate(task.CreatedAt), tc.created},
+			{"completed", formatOptionalDate(task.CompletedAt), tc.completed},
+			{"due", formatOptionalDate(task.Due), tc.due},
+		} {
+			if date.got != date.want {
+				t.Errorf("%q: %s = %q, want %q", tc.line, date.name, date.got, date.want)
+			}
+		}
+		if !slices.Equal(task.Tags, tc.tags) || !slices.Equal(task.Contexts, tc.contexts) {
+			t.Errorf("%q: tags, contexts = %q, %q, want %q, %q", tc.line, task.Tags, task.Contexts, tc.tags, tc.contexts)
+		}
+		if task.Project != tc.project {
+			t.Errorf("%q: project = %q, want %q", tc.line, task.Project, tc.project)
+		}
+		if !maps.Equal(task.Extras, tc.extras) {
+			t.Errorf("%q: extras = %v, want %v", tc.line, task.Extras, tc.extras)
+		}
+	}
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	lines := []string{
+		"Meet at 10:30 with Bob",
+		"(A) 2026-10-01 Call +family mom @phone about due:2026-10-20 the trip",
+		"x 2026-10-05 2026-10-01 Pay +bills rent pri:D custom:val today",
+		"(D) see https://example.com for ref:abc details",
+		"(C) 2026-10-02 recur:weekly Water +garden plants",
+	}
+	for _, line := range lines {
+		if got := formatTodoTxtLine(parseTodoTxtLine(line)); got != line {
+			t.Errorf("round trip of %q gave %q", line, got)
+		}
+	}
+}
+
+func TestFormatTodoTxtLineAfterEdit(t *testing.T) {
+	task := parseTodoTxtLine("(A) Call +family mom @phone")
+	task.Tags = append(task.Tags, "urgent")
+	if got, want := formatTodoTxtLine(task), "(A) Call mom +family +urgent @phone"; got != want {
+		t.Errorf("edited task = %q, want %q", got, want)
+	}
+
+	task = parseTodoTxtLine("Call +family mom")
+	task.Description = "Call dad"
+	if got := formatTodoTxtLine(task); !strings.HasPrefix(got, "Call dad") || strings.Contains(got, "mom") {
+		t.Errorf("renamed task = %q, want the new description", got)
+	}
+}
+
+// formatOptionalDate formats a date as YYYY-MM-DD, or "" for none.
+func formatOptionalDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format(dueDateLayout)
+}
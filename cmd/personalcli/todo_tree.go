@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// childrenByParent groups task IDs under their parent's ID. Top-level tasks
+// are grouped under 0.
+func childrenByParent(tasks []Task) map[int][]int {
+	children := map[int][]int{}
+	for _, task := range tasks {
+		children[task.Parent] = append(children[task.Parent], task.ID)
+	}
+	return children
+}
+
+// descendants returns the IDs of every task below id in the hierarchy.
+func descendants(tasks []Task, id int) []int {
+	children := childrenByParent(tasks)
+	var ids []int
+	seen := map[int]bool{id: true}
+	queue := []int{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, child := range children[current] {
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+			ids = append(ids, child)
+			queue = append(queue, child)
+		}
+	}
+	return ids
+}
+
+// openChildren returns the IDs of direct children of id that are not completed.
+func openChildren(tasks []Task, id int) []int {
+	var open []int
+	for _, task := range tasks {
+		if task.Parent == id && !task.isDone() {
+			open = append(open, task.ID)
+		}
+	}
+	return open
+}
+
+// validateParent checks that parent exists and that making it the parent of
+// id would not create a loop. An id of 0 means a task that is not saved yet.
+func validateParent(tasks []Task, id, parent int) error {
+	if parent == 0 {
+		return nil
+	}
+	if findTask(tasks, parent) == -1 {
+		return fmt.Errorf("parent task %d not found", parent)
+	}
+	if id == 0 {
+		return nil
+	}
+	if parent == id {
+		return fmt.Errorf("task %d cannot be its own parent", id)
+	}
+	for _, d := range descendants(tasks, id) {
+		if d == parent {
+			return fmt.Errorf("task %d is a subtask of %d and cannot be its parent", parent, id)
+		}
+	}
+	return nil
+}
+
+// renderTaskTree formats the visible tasks as an indented tree. Tasks whose
+// parent is not visible are shown at the top level. Progress counts such as
+// [2/5] are computed from all tasks so filtering does not skew them.
+func renderTaskTree(visible, all []Task, now time.Time) []string {
+	shown := map[int]bool{}
+	for _, task := range visible {
+		shown[task.ID] = true
+	}
+
+	var roots []Task
+	children := map[int][]Task{}
+	for _, task := range visible {
+		if task.Parent != 0 && shown[task.Parent] {
+			children[task.Parent] = append(children[task.Parent], task)
+		} else {
+			roots = append(roots, task)
+		}
+	}
+
+	var lines []string
+	printed := map[int]bool{}
+	var walk func(task Task, depth int)
+	walk = func(task Task, depth int) {
+		if printed[task.ID] {
+			return
+		}
+		printed[task.ID] = true
+
+		line := strings.Repeat("    ", depth) + formatTask(task, now)
+		var done, total int
+		for _, t := range all {
+			if t.Parent == task.ID {
+				total++
+				if t.isDone() {
+					done++
+				}
+			}
+		}
+		if total > 0 {
+			line += fmt.Sprintf(" [%d/%d]", done, total)
+		}
+		lines = append(lines, line)
+
+		for _, child := range children[task.ID] {
+			walk(child, depth+1)
+		}
+	}
+	for _, root := range roots {
+		walk(root, 0)
+	}
+	// Tasks caught in a parent loop have no root; show them rather than drop them.
+	for _, task := range visible {
+		walk(task, 0)
+	}
+	return lines
+}
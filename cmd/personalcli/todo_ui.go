@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// uiMode is what the interactive view's keyboard input currently drives.
+type uiMode int
+
+const (
+	uiBrowse uiMode = iota
+	uiFilter
+	uiAdd
+	uiEdit
+)
+
+// uiKey is a decoded key press: either a printable rune or a named key such
+// as "up", "enter" or "esc".
+type uiKey struct {
+	r    rune
+	name string
+}
+
+// uiHelp is shown at the bottom of the screen while browsing.
+const uiHelp = "↑/↓ j/k move  space done  a add  e edit  / filter  J/K reorder  q quit"
+
+// ansiPattern matches terminal escape sequences so lines can be measured.
+var ansiPattern = regexp.MustCompile("\033\\[[0-9;?]*[A-Za-z]")
+
+// todoUI is the state of the interactive task view. Tasks are shown in file
+// order; every change is written back immediately through saveTasks, so it
+// can be undone with "todo undo" like any other command.
+type todoUI struct {
+	tasks   []Task
+	query   taskQuery
+	filter  string
+	visible []int // indexes into tasks
+	cursor  int
+	offset  int
+	mode    uiMode
+	input   []rune
+	message string
+	quit    bool
+}
+
+// refresh recomputes the visible tasks and keeps the cursor on one of them.
+func (u *todoUI) refresh() {
+	u.visible = u.visible[:0]
+	for i, task := range u.tasks {
+		if u.query.matches(task) {
+			u.visible = append(u.visible, i)
+		}
+	}
+	u.cursor = max(0, min(u.cursor, len(u.visible)-1))
+}
+
+// selected returns the index in tasks of the task under the cursor, or -1.
+func (u *todoUI) selected() int {
+	if len(u.visible) == 0 {
+		return -1
+	}
+	return u.visible[u.cursor]
+}
+
+// selectID moves the cursor to the task with the given ID if it is visible.
+func (u *todoUI) selectID(id int) {
+	for i, index := range u.visible {
+		if u.tasks[index].ID == id {
+			u.cursor = i
+			return
+		}
+	}
+}
+
+func (u *todoUI) save(op string) error {
+	if err := saveTasks(op, u.tasks); err != nil {
+		return fmt.Errorf("writing tasks: %v", err)
+	}
+	return nil
+}
+
+// handleKey applies one key press. Errors are only returned when the tasks
+// file could not be written; problems with the input itself are shown in the
+// status line.
+func (u *todoUI) handleKey(key uiKey) error {
+	u.message = ""
+	if key.name == "ctrl-c" {
+		u.quit = true
+		return nil
+	}
+	if u.mode != uiBrowse {
+		return u.handleInput(key)
+	}
+
+	switch {
+	case key.name == "up" || key.r == 'k':
+		u.cursor = max(u.cursor-1, 0)
+	case key.name == "down" || key.r == 'j':
+		u.cursor = min(u.cursor+1, max(len(u.visible)-1, 0))
+	case key.r == 'g' || key.name == "home":
+		u.cursor = 0
+	case key.r == 'G' || key.name == "end":
+		u.cursor = max(len(u.visible)-1, 0)
+	case key.r == 'q' || key.name == "esc":
+		u.quit = true
+	case key.r == ' ' || key.r == 'x':
+		return u.toggle()
+	case key.r == 'a':
+		u.mode, u.input = uiAdd, nil
+	case key.r == 'e':
+		if i := u.selected(); i != -1 {
+			u.mode, u.input = uiEdit, []rune(u.tasks[i].Description)
+		}
+	case key.r == '/':
+		u.mode, u.input = uiFilter, []rune(u.filter)
+	case key.r == 'J':
+		return u.reorder(1)
+	case key.r == 'K':
+		return u.reorder(-1)
+	}
+	return nil
+}
+
+// handleInput edits the prompt line while adding, editing or filtering.
+func (u *todoUI) handleInput(key uiKey) error {
+	switch key.name {
+	case "esc":
+		if u.mode == uiFilter {
+			u.setFilter("")
+		}
+		u.mode = uiBrowse
+		return nil
+	case "enter":
+		mode := u.mode
+		u.mode = uiBrowse
+		switch mode {
+		case uiAdd:
+			return u.add(string(u.input))
+		case uiEdit:
+			return u.edit(string(u.input))
+		}
+		return nil
+	case "backspace":
+		if len(u.input) > 0 {
+			u.input = u.input[:len(u.input)-1]
+		}
+	case "":
+		if unicode.IsPrint(key.r) {
+			u.input = append(u.input, key.r)
+		}
+	}
+	// The list narrows as the filter is typed.
+	if u.mode == uiFilter {
+		u.setFilter(string(u.input))
+	}
+	return nil
+}
+
+// setFilter applies a query typed into the filter prompt. While the query
+// is incomplete or invalid the previous filter stays in effect.
+func (u *todoUI) setFilter(text string) {
+	query, err := parseQuery(text, currentTime())
+	if err != nil {
+		u.message = "Filter: " + err.Error()
+		return
+	}
+	u.filter, u.query = text, query
+	u.refresh()
+}
+
+// toggle completes the selected task, or reopens it if it is done.
+func (u *todoUI) toggle() error {
+	i := u.selected()
+	if i == -1 {
+		return nil
+	}
+	task := &u.tasks[i]
+	if task.isDone() {
+		task.reopen()
+		u.message = fmt.Sprintf("Reopened task %d.", task.ID)
+		return u.save(fmt.Sprintf("undone %d", task.ID))
+	}
+	if open := openChildren(u.tasks, task.ID); len(open) > 0 {
+		u.message = fmt.Sprintf("Task %d has %d open subtask(s). Finish them first.", task.ID, len(open))
+		return nil
+	}
+
+	id := task.ID
+	task.complete(time.Now())
+	tasks, next, err := scheduleNext(u.tasks, i, currentTime())
+	if err != nil {
+		task.reopen()
+		u.message = "Error scheduling next occurrence: " + err.Error()
+		return nil
+	}
+	u.tasks = tasks
+	u.message = fmt.Sprintf("Completed task %d.", id)
+	if next != nil {
+		u.message += fmt.Sprintf(" Next occurrence is task %d, due %s.", next.ID, formatDue(*next.Due))
+	}
+	u.refresh()
+	return u.save(fmt.Sprintf("done %d", id))
+}
+
+// add creates a task from a line written like "todo add" arguments.
+func (u *todoUI) add(text string) error {
+	description, tags, project := parseTaskTokens(strings.Fields(text))
+	if description == "" {
+		u.message = "Task description cannot be empty."
+		return nil
+	}
+	now := time.Now()
+	task := Task{
+		ID:          nextTaskID(u.tasks),
+		Description: description,
+		Status:      statusTodo,
+		CreatedAt:   &now,
+		Tags:        tags,
+		Project:     project,
+	}
+	u.tasks = append(u.tasks, task)
+	u.refresh()
+	u.selectID(task.ID)
+	u.message = fmt.Sprintf("Added task %d.", task.ID)
+	return u.save(fmt.Sprintf("add %d", task.ID))
+}
+
+// edit replaces the selected task's description; +tag and project:name
+// tokens add tags and set the project as in "todo edit".
+func (u *todoUI) edit(text string) error {
+	i := u.selected()
+	if i == -1 {
+		return nil
+	}
+	description, tags, project := parseTaskTokens(strings.Fields(text))
+	task := &u.tasks[i]
+	if description != "" {
+		task.Description = description
+	}
+	for _, tag := range tags {
+		task.Tags = addTag(task.Tags, tag)
+	}
+	if project != "" {
+		task.Project = project
+	}
+	id := task.ID
+	u.refresh()
+	u.selectID(id)
+	u.message = fmt.Sprintf("Updated task %d.", id)
+	return u.save(fmt.Sprintf("edit %d", id))
+}
+
+// reorder swaps the selected task with the next (delta 1) or previous
+// (delta -1) visible task in the tasks file.
+func (u *todoUI) reorder(delta int) error {
+	other := u.cursor + delta
+	if len(u.visible) == 0 || other < 0 || other >= len(u.visible) {
+		return nil
+	}
+	a, b := u.visible[u.cursor], u.visible[other]
+	u.tasks[a], u.tasks[b] = u.tasks[b], u.tasks[a]
+	u.cursor = other
+	return u.save(fmt.Sprintf("reorder %d", u.tasks[b].ID))
+}
+
+// truncate shortens s to at most width runes.
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max(width-1, 0)]) + "…"
+}
+
+// render draws the whole screen for a terminal of the given size.
+func (u *todoUI) render(w io.Writer, width, height int) {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+
+	title := fmt.Sprintf("Todo: %s list, %d of %d tasks", currentList, len(u.visible), len(u.tasks))
+	if u.filter != "" {
+		title += " matching " + u.filter
+	}
+	b.WriteString("\033[1m" + truncate(title, width) + "\033[0m\r\n")
+
+	rows := max(height-3, 1)
+	if u.cursor < u.offset {
+		u.offset = u.cursor
+	}
+	if u.cursor >= u.offset+rows {
+		u.offset = u.cursor - rows + 1
+	}
+	now := currentTime()
+	for row := 0; row < rows; row++ {
+		n := u.offset + row
+		if n >= len(u.visible) {
+			if n == 0 {
+				b.WriteString("No tasks. Press a to add one.")
+			}
+			b.WriteString("\r\n")
+			continue
+		}
+		task := u.tasks[u.visible[n]]
+		line := truncate(ansiPattern.ReplaceAllString(formatTask(task, now), ""), width)
+		switch {
+		case n == u.cursor:
+			line = "\033[7m" + padRight(line, width) + "\033[0m"
+		case isOverdue(task, now):
+			line = "\033[31m" + line + "\033[0m"
+		}
+		b.WriteString(line + "\r\n")
+	}
+
+	b.WriteString("\r\n")
+	switch u.mode {
+	case uiAdd:
+		b.WriteString(truncate("Add: "+string(u.input), width-1) + "█")
+	case uiEdit:
+		b.WriteString(truncate("Edit: "+string(u.input), width-1) + "█")
+	case uiFilter:
+		b.WriteString(truncate("Filter: "+string(u.input), width-1) + "█")
+	default:
+		if u.message != "" {
+			b.WriteString(truncate(u.message, width))
+		} else {
+			b.WriteString("\033[2m" + truncate(uiHelp, width) + "\033[0m")
+		}
+	}
+	io.WriteString(w, b.String())
+}
+
+// readKey reads one key press from a terminal in raw mode.
+func readKey(r *bufio.Reader) (uiKey, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return uiKey{}, err
+	}
+	switch c {
+	case 3:
+		return uiKey{name: "ctrl-c"}, nil
+	case '\r', '\n':
+		return uiKey{name: "enter"}, nil
+	case 127, 8:
+		return uiKey{name: "backspace"}, nil
+	case 27:
+		// A lone escape is the Esc key; arrows arrive as ESC [ A and friends.
+		if r.Buffered() == 0 {
+			return uiKey{name: "esc"}, nil
+		}
+		if next, _, _ := r.ReadRune(); next != '[' && next != 'O' {
+			return uiKey{name: "esc"}, nil
+		}
+		code, _, _ := r.ReadRune()
+		switch code {
+		case 'A':
+			return uiKey{name: "up"}, nil
+		case 'B':
+			return uiKey{name: "down"}, nil
+		case 'H':
+			return uiKey{name: "home"}, nil
+		case 'F':
+			return uiKey{name: "end"}, nil
+		}
+		// Skip the rest of sequences we do not use, such as ESC [ 3 ~.
+		for code >= '0' && code <= '9' || code == ';' {
+			code, _, _ = r.ReadRune()
+		}
+		return uiKey{}, nil
+	}
+	return uiKey{r: c}, nil
+}
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Browse and edit tasks in an interactive full-screen view",
+	Long: `Browse and edit tasks in an interactive full-screen view:
+
+  ↑/↓ or j/k   move the cursor (g/G jump to the top/bottom)
+  space or x   complete the task, or reopen it
+  a            add a task (write it like "todo add": text +tag project:name)
+  e            edit the task's description
+  /            filter as you type with a "todo list" query; Esc clears it
+  J/K          move the task down/up ("todo list --sort manual" shows this order)
+  q            quit
+
+Changes are saved as they are made and can be undone with "todo undo". When
+not attached to a terminal, the list is printed instead.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tasks, err := readTasks()
+		if err != nil {
+			fmt.Println("Error reading tasks:", err)
+			os.Exit(1)
+		}
+
+		inFd, outFd := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+		if !term.IsTerminal(inFd) || !term.IsTerminal(outFd) {
+			if err := printTasks("Your tasks:", tasks, matchAll{}, "manual"); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		oldState, err := term.MakeRaw(inFd)
+		if err != nil {
+			fmt.Println("Error preparing terminal:", err)
+			os.Exit(1)
+		}
+		// Switch to the alternate screen and hide the cursor while running.
+		fmt.Print("\033[?1049h\033[?25l")
+		restore := func() {
+			fmt.Print("\033[?25h\033[?1049l")
+			term.Restore(inFd, oldState)
+		}
+
+		ui := &todoUI{tasks: tasks, query: matchAll{}}
+		ui.refresh()
+		keys := bufio.NewReader(os.Stdin)
+		for !ui.quit {
+			width, height, err := term.GetSize(outFd)
+			if err != nil {
+				width, height = 80, 24
+			}
+			ui.render(os.Stdout, width, height)
+
+			key, err := readKey(keys)
+			if err != nil {
+				break
+			}
+			if err := ui.handleKey(key); err != nil {
+				restore()
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+		restore()
+	},
+}
+
+func init() {
+	todoCmd.AddCommand(uiCmd)
+}